@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/reybrally/order-service/internal/domain/money"
+)
+
+var header = []string{"base", "quote", "rate", "valid_from"}
+
+func parseRates(r io.Reader) ([]money.Rate, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(header)
+	cr.TrimLeadingSpace = true
+
+	head, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	for i, col := range header {
+		if strings.ToLower(strings.TrimSpace(head[i])) != col {
+			return nil, fmt.Errorf("header must be %s", strings.Join(header, ","))
+		}
+	}
+
+	var out []money.Rate
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+
+		validFrom, err := parseValidFrom(strings.TrimSpace(rec[3]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rt, err := money.ParseRate(
+			strings.ToUpper(strings.TrimSpace(rec[0])),
+			strings.ToUpper(strings.TrimSpace(rec[1])),
+			rec[2], validFrom)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		out = append(out, rt)
+	}
+}
+
+func parseValidFrom(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid valid_from %q (2006-01-02 or RFC3339 expected)", s)
+	}
+	return t, nil
+}
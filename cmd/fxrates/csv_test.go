@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRates(t *testing.T) {
+	in := "base,quote,rate,valid_from\n" +
+		"usd,RUB,92.45,2025-01-01\n" +
+		"EUR, USD, 1.0832, 2025-01-02T12:00:00+03:00\n"
+
+	rates, err := parseRates(strings.NewReader(in))
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+
+	assert.Equal(t, "USD", rates[0].Base)
+	assert.Equal(t, "RUB", rates[0].Quote)
+	assert.Equal(t, "92.45", rates[0].Decimal())
+	assert.True(t, rates[0].ValidFrom.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	assert.Equal(t, "1.0832", rates[1].Decimal())
+	assert.True(t, rates[1].ValidFrom.Equal(time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestParseRatesErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad header":     "from,to,rate,date\n",
+		"unknown code":   "base,quote,rate,valid_from\nUSD,XXZ,1,2025-01-01\n",
+		"zero rate":      "base,quote,rate,valid_from\nUSD,EUR,0,2025-01-01\n",
+		"bad date":       "base,quote,rate,valid_from\nUSD,EUR,1,01/02/2025\n",
+		"missing column": "base,quote,rate,valid_from\nUSD,EUR,1\n",
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseRates(strings.NewReader(in))
+			assert.Error(t, err)
+		})
+	}
+}
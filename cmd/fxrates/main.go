@@ -0,0 +1,57 @@
+// Command fxrates loads exchange rates from a CSV file into exchange_rates.
+//
+//	fxrates -file rates.csv
+//
+// The file has a header row and the columns base,quote,rate,valid_from, where
+// valid_from is a date (2006-01-02, midnight UTC) or an RFC 3339 timestamp.
+// Loading the same file twice is harmless: rows with the same pair and
+// valid_from are replaced. The database is configured like the server's:
+// DATABASE_URL, or DB_HOST, DB_PORT, DB_NAME, DB_USER, DB_PASSWORD and
+// DB_SSLMODE.
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/reybrally/order-service/internal/adapters/repo"
+	"github.com/reybrally/order-service/internal/config"
+)
+
+func main() {
+	file := flag.String("file", "-", "CSV file with rates, - for stdin")
+	flag.Parse()
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("open: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	rates, err := parseRates(in)
+	if err != nil {
+		log.Fatalf("parse %s: %v", *file, err)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, config.Load().DB.DSN())
+	if err != nil {
+		log.Fatalf("db connect: %v", err)
+	}
+	defer pool.Close()
+
+	if err := repo.NewExchangeRateRepo(pool).SaveRates(ctx, rates); err != nil {
+		log.Fatalf("save rates: %v", err)
+	}
+	log.Printf("loaded %d exchange rates\n", len(rates))
+}
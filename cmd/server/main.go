@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -20,7 +21,10 @@ import (
 	"github.com/reybrally/order-service/internal/adapters/cache"
 	httpHandlers "github.com/reybrally/order-service/internal/adapters/http/handlers"
 	kaf "github.com/reybrally/order-service/internal/adapters/kafka"
+	"github.com/reybrally/order-service/internal/adapters/kafka/ingest"
 	repoPkg "github.com/reybrally/order-service/internal/adapters/repo"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
 	"github.com/reybrally/order-service/internal/logging"
 	svcPkg "github.com/reybrally/order-service/internal/services"
 )
@@ -51,8 +55,8 @@ func main() {
 		})
 		logging.LogInfo("redis cache enabled", logrus.Fields{"addr": cfg.Redis.Addr, "ttl": cfg.Redis.TTL.String()})
 	} else {
-		cacheService = cache.NewCacheService(1000)
-		logging.LogInfo("lru cache enabled", logrus.Fields{"capacity": 1000})
+		cacheService = cache.NewCacheService(cfg.Cache.Capacity)
+		logging.LogInfo("lru cache enabled", logrus.Fields{"capacity": cfg.Cache.Capacity})
 	}
 
 	prod := mustKafkaProducer(cfg)
@@ -60,10 +64,55 @@ func main() {
 
 	eventsTopic := getenv("ORDERS_EVENTS_TOPIC", "orders-events")
 
-	svc := svcPkg.NewOrderService(repo, cacheService, prod, eventsTopic)
-	h := httpHandlers.NewOrderHandlers(svc)
+	consistency, err := domain.ParseConsistencyMode(cfg.App.ConsistencyMode)
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+	switch cfg.App.EmailValidation {
+	case "strict", "lenient":
+		validation.SetEmailFormatCheck(cfg.App.EmailValidation == "strict")
+	default:
+		log.Fatalf("config: unknown ORDER_EMAIL_VALIDATION %q (strict or lenient expected)", cfg.App.EmailValidation)
+	}
+	svc := svcPkg.NewOrderService(repo, cacheService, repoPkg.NewExchangeRateRepo(pool), eventsTopic, consistency)
+	h := httpHandlers.NewOrderHandlers(svc, httpHandlers.BulkLimits{
+		BatchSize:  cfg.Bulk.BatchSize,
+		MaxRecords: cfg.Bulk.MaxRecords,
+		MaxBytes:   cfg.Bulk.MaxBytes,
+		Timeout:    cfg.Bulk.Timeout,
+	})
+
+	var cacheWarm atomic.Bool
+	go func() {
+		defer cacheWarm.Store(true)
+		wCtx, wCancel := context.WithTimeout(ctx, cfg.Cache.WarmupTimeout)
+		defer wCancel()
+		if _, err := svc.WarmUpCache(wCtx, cfg.Cache.WarmupSize, cfg.Cache.WarmupBatch); err != nil {
+			logging.LogError("cache warm-up aborted", err, logrus.Fields{"timeout": cfg.Cache.WarmupTimeout.String()})
+		}
+	}()
+
+	relay := kaf.NewOutboxRelay(repoPkg.NewOutboxRepo(pool), prod, kaf.OutboxRelayConfig{
+		PollInterval:  cfg.Outbox.PollInterval,
+		BatchSize:     cfg.Outbox.BatchSize,
+		MaxAttempts:   cfg.Outbox.MaxAttempts,
+		Backoff:       cfg.Outbox.Backoff,
+		SentRetention: cfg.Outbox.SentRetention,
+		SendTimeout:   cfg.Outbox.SendTimeout,
+		Lease:         cfg.Outbox.Lease,
+	})
+	go func() {
+		if err := relay.Run(ctx); err != nil {
+			logging.LogError("outbox relay stopped", err, logrus.Fields{})
+		}
+	}()
+
+	go svc.RunDeletedPurger(ctx, cfg.Retention.PurgeInterval, cfg.Retention.DeletedOrders)
+
+	idempotency := repoPkg.NewIdempotencyRepo(pool)
+	go svcPkg.RunIdempotencyPurger(ctx, idempotency, cfg.Idempotency.PurgeInterval)
 
-	consumer := kaf.NewConsumer(kaf.ConsumerConfig{
+	consumerCfg := kaf.ConsumerConfig{
 		Brokers:           cfg.Kafka.Brokers,
 		ClientID:          "order-service",
 		MinBytes:          1 << 10,
@@ -75,7 +124,21 @@ func main() {
 		StartOffset:       segmentio.FirstOffset,
 		MaxRetries:        5,
 		Backoff:           200 * time.Millisecond,
-	})
+		OffsetStore:       repoPkg.NewConsumerOffsetRepo(pool),
+	}
+	consumer := kaf.NewConsumer(consumerCfg)
+
+	refreshCache := func(ctx context.Context, uid string) error {
+		ord, err := repo.GetOrder(ctx, uid)
+		if err != nil {
+			return err
+		}
+		if err := cacheService.Set(uid, ord); err != nil {
+			return err
+		}
+		logging.LogInfo("cache-projector cached", logrus.Fields{"order_uid": uid})
+		return nil
+	}
 
 	go func() {
 		group := getenv("ORDERS_CONSUMER_GROUP", "order-service-cache-projector")
@@ -91,15 +154,23 @@ func main() {
 					logging.LogError("cache-projector bad payload (upserted)", err, logrus.Fields{})
 					return nil
 				}
-				ord, err := repo.GetOrder(ctx, p.OrderUID)
-				if err != nil {
-					return err
+				return refreshCache(ctx, p.OrderUID)
+
+			case "order.restored":
+				var p kaf.OrderRestored
+				if err := json.Unmarshal(msg.Envelope.Payload, &p); err != nil {
+					logging.LogError("cache-projector bad payload (restored)", err, logrus.Fields{})
+					return nil
 				}
-				if err := cacheService.Set(p.OrderUID, ord); err != nil {
-					return err
+				return refreshCache(ctx, p.OrderUID)
+
+			case "order.status_changed":
+				var p kaf.OrderStatusChanged
+				if err := json.Unmarshal(msg.Envelope.Payload, &p); err != nil {
+					logging.LogError("cache-projector bad payload (status_changed)", err, logrus.Fields{})
+					return nil
 				}
-				logging.LogInfo("cache-projector cached", logrus.Fields{"order_uid": p.OrderUID})
-				return nil
+				return refreshCache(ctx, p.OrderUID)
 
 			case "order.deleted":
 				var p kaf.OrderDeleted
@@ -125,24 +196,66 @@ func main() {
 		}
 	}()
 
+	ingestCfg := consumerCfg
+	ingestCfg.RawPayload = true
+	ingestCfg.DLQTopic = cfg.Kafka.DLQ
+	ingestCfg.DLQProducer = prod
+	ingestConsumer := kaf.NewConsumer(ingestCfg)
+
+	if cfg.Kafka.IngestTopic != "" {
+		go func() {
+			topic, group := cfg.Kafka.IngestTopic, cfg.Kafka.IngestGroup
+			logging.LogInfo("order ingest consumer subscribing", logrus.Fields{
+				"topic": topic, "group": group, "dlq": cfg.Kafka.DLQ,
+			})
+			if err := ingestConsumer.Subscribe(ctx, topic, group, ingest.NewOrderIngestor(svc).Handle); err != nil {
+				logging.LogError("order ingest consumer stopped", err, logrus.Fields{"topic": topic, "group": group})
+			} else {
+				logging.LogInfo("order ingest consumer exited gracefully", logrus.Fields{"topic": topic, "group": group})
+			}
+		}()
+	}
+
 	r := chi.NewRouter()
-	r.Use(middleware.RequestID, middleware.RealIP, middleware.Recoverer, middleware.StripSlashes, middleware.Timeout(5*time.Second))
-	r.Get("/health", httpHandlers.HealthHandler)
-	r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
-		if err := pool.Ping(r.Context()); err != nil {
-			logging.LogError("readiness: db not ready", err, logrus.Fields{})
-			http.Error(w, "db not ready: "+err.Error(), http.StatusServiceUnavailable)
-			return
-		}
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
-	r.Route("/orders", func(r chi.Router) {
-		r.Post("/", h.CreateOrUpdateOrder)
-		r.Put("/", h.CreateOrUpdateOrder)
-		r.Get("/search", h.SearchOrders)
-		r.Get("/{id}", h.GetHandler)
-		r.Delete("/{id}", h.DeleteHandler)
+	r.Use(middleware.RequestID, httpHandlers.ActorMiddleware, middleware.RealIP, middleware.Recoverer, middleware.StripSlashes)
+	// Exports stream for as long as the client reads, so they have no request
+	// timeout; bulk imports get their own, longer one.
+	r.Get("/orders/export", h.ExportHandler)
+	r.With(middleware.Timeout(cfg.Bulk.Timeout)).Post("/orders/bulk", h.BulkImportHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(5 * time.Second))
+		r.Get("/health", httpHandlers.HealthHandler)
+		r.Get("/ready", func(w http.ResponseWriter, r *http.Request) {
+			if !cacheWarm.Load() {
+				http.Error(w, "cache warming up", http.StatusServiceUnavailable)
+				return
+			}
+			if err := pool.Ping(r.Context()); err != nil {
+				logging.LogError("readiness: db not ready", err, logrus.Fields{})
+				http.Error(w, "db not ready: "+err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		})
+		r.Get("/tracking/{track_number}", h.TrackingHandler)
+		r.Route("/orders", func(r chi.Router) {
+			r.With(httpHandlers.IdempotencyMiddleware(idempotency, cfg.Idempotency.TTL, cfg.Idempotency.Lock)).Post("/", h.CreateOrUpdateOrder)
+			r.Put("/", h.CreateOrUpdateOrder)
+			r.Get("/", h.ListByIDsHandler)
+			r.Post("/batch-get", h.BatchGetHandler)
+			r.Get("/search", h.SearchOrders)
+			r.Get("/stats", h.StatsHandler)
+			r.Get("/consistency", h.ConsistencyReportHandler)
+			r.Get("/{id}", h.GetHandler)
+			r.Patch("/{id}", h.PatchHandler)
+			r.Delete("/{id}", h.DeleteHandler)
+			r.Post("/{id}/restore", h.RestoreHandler)
+			r.Post("/{id}/transitions", h.TransitionHandler)
+			r.Get("/{id}/transitions", h.TransitionHistoryHandler)
+			r.Get("/{id}/history", h.HistoryHandler)
+			r.Get("/{id}/history/{rev}", h.RevisionHandler)
+		})
 	})
 
 	srv := &http.Server{
@@ -165,12 +278,16 @@ func main() {
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-stop
 	logging.LogInfo("shutdown signal received", logrus.Fields{"signal": sig.String()})
+	cancel()
 
 	if err := consumer.Close(); err != nil {
 		logging.LogError("kafka consumer close failed", err, logrus.Fields{})
 	} else {
 		logging.LogInfo("kafka consumer closed", logrus.Fields{})
 	}
+	if err := ingestConsumer.Close(); err != nil {
+		logging.LogError("order ingest consumer close failed", err, logrus.Fields{})
+	}
 
 	shCtx, shCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shCancel()
@@ -183,11 +300,9 @@ func main() {
 }
 
 func mustPG(ctx context.Context, cfg config.Config) *pgxpool.Pool {
-	dbURL := os.Getenv("DATABASE_URL")
+	dbURL := cfg.DB.DSN()
 	fields := logrus.Fields{}
-	if dbURL == "" {
-		dbURL = "postgres://" + cfg.DB.User + ":" + cfg.DB.Password + "@" +
-			cfg.DB.Host + ":" + cfg.DB.Port + "/" + cfg.DB.Name + "?sslmode=" + cfg.DB.SSLMode
+	if cfg.DB.URL == "" {
 		fields = logrus.Fields{
 			"source":  "env/defaults",
 			"host":    cfg.DB.Host,
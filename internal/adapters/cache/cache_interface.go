@@ -2,8 +2,16 @@ package cache
 
 import domain "github.com/reybrally/order-service/internal/domain/order"
 
+// Cache stores orders by order_uid. It also keeps a track number index, but
+// track numbers are not unique, so only SetTrack writes it, with the order the
+// repository resolved the track number to. Set of any order with that track
+// number drops the entry, as the order may now be the one to serve. The index
+// may still lag behind a changed track number, so callers check the order
+// they get back.
 type Cache interface {
 	Set(key string, o domain.Order) error
 	Get(key string) (domain.Order, error)
 	Delete(key string) error
+	SetTrack(trackNumber, key string) error
+	GetUIDByTrack(trackNumber string) (string, error)
 }
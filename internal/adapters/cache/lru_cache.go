@@ -17,6 +17,7 @@ type lruNode struct {
 type CacheService struct {
 	mu       sync.Mutex
 	cache    map[string]*lruNode
+	tracks   map[string]string
 	head     *lruNode
 	tail     *lruNode
 	capacity int
@@ -28,6 +29,7 @@ func NewCacheService(capacity int) *CacheService {
 	}
 	return &CacheService{
 		cache:    make(map[string]*lruNode, capacity),
+		tracks:   make(map[string]string, capacity),
 		capacity: capacity,
 	}
 }
@@ -36,7 +38,9 @@ func (c *CacheService) Set(key string, value order.Order) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	delete(c.tracks, value.TrackNumber)
 	if nd, ok := c.cache[key]; ok {
+		c.unindexTrack(nd)
 		nd.value = value
 		c.moveToTail(nd)
 		return nil
@@ -73,15 +77,45 @@ func (c *CacheService) Delete(key string) error {
 		return orders.ErrNotFound
 	}
 	c.unlink(nd)
+	c.unindexTrack(nd)
 	delete(c.cache, key)
 	return nil
 }
 
+// SetTrack points trackNumber at the cached order key. It fails with
+// orders.ErrNotFound unless key is cached with that track number, so that the
+// entry leaves the index together with the order.
+func (c *CacheService) SetTrack(trackNumber, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	nd, ok := c.cache[key]
+	if !ok || nd.value.TrackNumber != trackNumber {
+		return orders.ErrNotFound
+	}
+	c.tracks[trackNumber] = key
+	return nil
+}
+
+// GetUIDByTrack returns the order_uid of the cached order with trackNumber
+// without touching its recency.
+func (c *CacheService) GetUIDByTrack(trackNumber string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	uid, ok := c.tracks[trackNumber]
+	if !ok {
+		return "", orders.ErrNotFound
+	}
+	return uid, nil
+}
+
 func (c *CacheService) Clear() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.cache = make(map[string]*lruNode, c.capacity)
+	c.tracks = make(map[string]string, c.capacity)
 	c.head = nil
 	c.tail = nil
 }
@@ -118,9 +152,17 @@ func (c *CacheService) evictHead() {
 	}
 	evicted := c.head
 	c.unlink(evicted)
+	c.unindexTrack(evicted)
 	delete(c.cache, evicted.key)
 }
 
+// unindexTrack drops the track entry of nd unless it points at another order.
+func (c *CacheService) unindexTrack(nd *lruNode) {
+	if c.tracks[nd.value.TrackNumber] == nd.key {
+		delete(c.tracks, nd.value.TrackNumber)
+	}
+}
+
 func (c *CacheService) unlink(nd *lruNode) {
 	if nd == nil {
 		return
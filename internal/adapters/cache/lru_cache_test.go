@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"github.com/reybrally/order-service/internal/app/orders"
 	"github.com/reybrally/order-service/internal/domain/order"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -206,3 +207,60 @@ func TestLRUCacheEvictionWhenReachingMaxCapacity(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, mockOrder("c", 300), val)
 }
+
+func TestLRUCacheTrackIndex(t *testing.T) {
+	c := NewCacheService(2)
+
+	a := mockOrder("a", 100)
+	a.TrackNumber = "TRK-A"
+	require.NoError(t, c.Set("a", a))
+	_, err := c.GetUIDByTrack("TRK-A")
+	assert.Error(t, err, "Set must not index on its own")
+
+	assert.ErrorIs(t, c.SetTrack("TRK-A", "x"), orders.ErrNotFound)
+	assert.ErrorIs(t, c.SetTrack("TRK-B", "a"), orders.ErrNotFound)
+	require.NoError(t, c.SetTrack("TRK-A", "a"))
+	uid, err := c.GetUIDByTrack("TRK-A")
+	require.NoError(t, err)
+	assert.Equal(t, "a", uid)
+
+	a.TrackNumber = "TRK-A2"
+	require.NoError(t, c.Set("a", a))
+	_, err = c.GetUIDByTrack("TRK-A")
+	assert.Error(t, err, "changed track number must leave the index")
+
+	require.NoError(t, c.SetTrack("TRK-A2", "a"))
+	require.NoError(t, c.Delete("a"))
+	_, err = c.GetUIDByTrack("TRK-A2")
+	assert.Error(t, err, "deleted order must leave the index")
+
+	require.NoError(t, c.Set("a", a))
+	require.NoError(t, c.SetTrack("TRK-A2", "a"))
+	require.NoError(t, c.Set("b", mockOrder("b", 200)))
+	require.NoError(t, c.Set("c", mockOrder("c", 300)))
+	_, err = c.GetUIDByTrack("TRK-A2")
+	assert.Error(t, err, "evicted order must leave the index")
+}
+
+// Track numbers are not unique: caching another order with the same track
+// number drops the entry, since only the repository knows which one to serve.
+func TestLRUCacheTrackIndexSharedTrackNumber(t *testing.T) {
+	c := NewCacheService(3)
+
+	newer := mockOrder("newer", 100)
+	newer.TrackNumber = "TRK"
+	older := mockOrder("older", 200)
+	older.TrackNumber = "TRK"
+
+	require.NoError(t, c.Set("newer", newer))
+	require.NoError(t, c.SetTrack("TRK", "newer"))
+	require.NoError(t, c.Set("older", older))
+	_, err := c.GetUIDByTrack("TRK")
+	assert.Error(t, err)
+
+	require.NoError(t, c.SetTrack("TRK", "newer"))
+	require.NoError(t, c.Delete("older"))
+	uid, err := c.GetUIDByTrack("TRK")
+	require.NoError(t, err)
+	assert.Equal(t, "newer", uid, "deleting another order keeps the entry")
+}
@@ -40,6 +40,12 @@ func (c *RedisCache) makeKey(id string) string {
 	return c.prefix + id
 }
 
+// makeTrackKey is the key of the track number index entry, which holds the
+// order_uid and expires no later than the order.
+func (c *RedisCache) makeTrackKey(trackNumber string) string {
+	return c.prefix + "track:" + trackNumber
+}
+
 func (c *RedisCache) Set(id string, o domain.Order) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -49,7 +55,14 @@ func (c *RedisCache) Set(id string, o domain.Order) error {
 		return err
 	}
 
-	return c.rdb.Set(ctx, c.makeKey(id), data, c.ttl).Err()
+	_, err = c.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, c.makeKey(id), data, c.ttl)
+		if o.TrackNumber != "" {
+			p.Del(ctx, c.makeTrackKey(o.TrackNumber))
+		}
+		return nil
+	})
+	return err
 }
 
 func (c *RedisCache) Get(id string) (domain.Order, error) {
@@ -68,11 +81,34 @@ func (c *RedisCache) Get(id string) (domain.Order, error) {
 	return o, nil
 }
 
+// Delete removes the order and, if it is still cached, its track number
+// index entry; an entry left behind expires on its own.
 func (c *RedisCache) Delete(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	return c.rdb.Del(ctx, c.makeKey(id)).Err()
+	keys := []string{c.makeKey(id)}
+	if b, err := c.rdb.Get(ctx, c.makeKey(id)).Bytes(); err == nil {
+		var o domain.Order
+		if json.Unmarshal(b, &o) == nil && o.TrackNumber != "" {
+			keys = append(keys, c.makeTrackKey(o.TrackNumber))
+		}
+	}
+	return c.rdb.Del(ctx, keys...).Err()
+}
+
+func (c *RedisCache) SetTrack(trackNumber, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return c.rdb.Set(ctx, c.makeTrackKey(trackNumber), id, c.ttl).Err()
+}
+
+func (c *RedisCache) GetUIDByTrack(trackNumber string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return c.rdb.Get(ctx, c.makeTrackKey(trackNumber)).Result()
 }
 
 func (c *RedisCache) Close() error {
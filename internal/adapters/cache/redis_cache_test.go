@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRedis is a go-redis hook that answers GET, SET and DEL from a map
+// instead of a server, including inside MULTI/EXEC pipelines. It records the
+// TTL of every SET and fails any other command.
+type memRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+	ttls map[string]time.Duration
+}
+
+func newTestRedisCache(t *testing.T, ttl time.Duration) (*RedisCache, *memRedis) {
+	t.Helper()
+	m := &memRedis{data: map[string]string{}, ttls: map[string]time.Duration{}}
+	c := NewRedisCache(RedisConfig{Addr: "127.0.0.1:0", Prefix: "order:", TTL: ttl})
+	c.rdb.AddHook(m)
+	t.Cleanup(func() { _ = c.Close() })
+	return c, m
+}
+
+func (m *memRedis) DialHook(redis.DialHook) redis.DialHook {
+	return func(context.Context, string, string) (net.Conn, error) {
+		return nil, fmt.Errorf("memRedis: no server")
+	}
+}
+
+func (m *memRedis) ProcessHook(redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error { return m.do(cmd) }
+}
+
+func (m *memRedis) ProcessPipelineHook(redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := m.do(cmd); err != nil && err != redis.Nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (m *memRedis) do(cmd redis.Cmder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	args := cmd.Args()
+	key := func(i int) string { return fmt.Sprint(args[i]) }
+	switch c := cmd.(type) {
+	case *redis.StringCmd:
+		v, ok := m.data[key(1)]
+		if !ok {
+			c.SetErr(redis.Nil)
+			return redis.Nil
+		}
+		c.SetVal(v)
+	case *redis.StatusCmd:
+		if name := strings.ToLower(cmd.Name()); name == "multi" {
+			return nil
+		}
+		var v string
+		switch b := args[2].(type) {
+		case []byte:
+			v = string(b)
+		default:
+			v = fmt.Sprint(b)
+		}
+		m.data[key(1)] = v
+		m.ttls[key(1)] = 0
+		if len(args) == 5 && strings.EqualFold(key(3), "px") {
+			m.ttls[key(1)] = time.Duration(args[4].(int64)) * time.Millisecond
+		}
+		if len(args) == 5 && strings.EqualFold(key(3), "ex") {
+			m.ttls[key(1)] = time.Duration(args[4].(int64)) * time.Second
+		}
+		c.SetVal("OK")
+	case *redis.IntCmd:
+		var n int64
+		for i := 1; i < len(args); i++ {
+			if _, ok := m.data[key(i)]; ok {
+				delete(m.data, key(i))
+				delete(m.ttls, key(i))
+				n++
+			}
+		}
+		c.SetVal(n)
+	case *redis.SliceCmd: // exec
+	default:
+		err := fmt.Errorf("memRedis: unsupported command %v", args)
+		cmd.SetErr(err)
+		return err
+	}
+	return nil
+}
+
+func (m *memRedis) has(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.data[key]
+	return ok
+}
+
+func TestRedisCacheTrackIndex(t *testing.T) {
+	c, m := newTestRedisCache(t, 10*time.Minute)
+
+	a := mockOrder("a", 100)
+	a.TrackNumber = "TRK-A"
+	require.NoError(t, c.Set("a", a))
+	got, err := c.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, a, got)
+	_, err = c.GetUIDByTrack("TRK-A")
+	assert.ErrorIs(t, err, redis.Nil, "Set must not index on its own")
+
+	require.NoError(t, c.SetTrack("TRK-A", "a"))
+	assert.True(t, m.has("order:track:TRK-A"), "index key is prefixed")
+	assert.Equal(t, 10*time.Minute, m.ttls["order:track:TRK-A"], "index entry expires with the order")
+	uid, err := c.GetUIDByTrack("TRK-A")
+	require.NoError(t, err)
+	assert.Equal(t, "a", uid)
+
+	// Another order with the same track number may be the one to serve.
+	b := mockOrder("b", 200)
+	b.TrackNumber = "TRK-A"
+	require.NoError(t, c.Set("b", b))
+	_, err = c.GetUIDByTrack("TRK-A")
+	assert.ErrorIs(t, err, redis.Nil)
+
+	require.NoError(t, c.SetTrack("TRK-A", "a"))
+	require.NoError(t, c.Delete("a"))
+	assert.False(t, m.has("order:a"))
+	_, err = c.GetUIDByTrack("TRK-A")
+	assert.ErrorIs(t, err, redis.Nil, "Delete drops the index entry of the order")
+
+	require.NoError(t, c.Delete("missing"))
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"net/http"
+	"strings"
+)
+
+const actorHeader = "X-Actor"
+
+// ActorMiddleware attaches the caller (X-Actor header) and chi's request id to
+// the request context so that order revisions can record who made a change.
+// It must run after middleware.RequestID.
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimSpace(r.Header.Get(actorHeader))
+		if name == "" {
+			name = "anonymous"
+		}
+		ctx := orders.WithActor(r.Context(), orders.Actor{
+			Name:      name,
+			RequestID: middleware.GetReqID(r.Context()),
+			Source:    "http",
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
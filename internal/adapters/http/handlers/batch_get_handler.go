@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// maxBatchGetIDs caps the ids resolved by one batch get.
+const maxBatchGetIDs = 500
+
+type BatchGetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchGetResponse lists the found orders in request order and the ids that
+// have no live order.
+type BatchGetResponse struct {
+	Orders  []OrderResponse `json:"orders"`
+	Missing []string        `json:"missing"`
+}
+
+// BatchGetHandler serves POST /orders/batch-get with a body {"ids": [...]}.
+func (h *OrderHandlers) BatchGetHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	defer r.Body.Close()
+
+	var req BatchGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.LogError("Error decoding request body", err, logrus.Fields{"method": "BatchGet"})
+		writeBodyErr(w, r, err)
+		return
+	}
+	h.batchGet(w, r, req.IDs)
+}
+
+// ListByIDsHandler serves GET /orders?ids=a,b (or ids=a&ids=b), the same
+// lookup as BatchGetHandler for short id lists.
+func (h *OrderHandlers) ListByIDsHandler(w http.ResponseWriter, r *http.Request) {
+	h.batchGet(w, r, queryList(r.URL.Query(), "ids"))
+}
+
+func (h *OrderHandlers) batchGet(w http.ResponseWriter, r *http.Request, raw []string) {
+	ids := make([]string, 0, len(raw))
+	for _, id := range raw {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	switch {
+	case len(ids) == 0:
+		logging.LogError("No ids given for batch get", nil, logrus.Fields{"method": "BatchGet"})
+		writeProblem(w, r, probBadRequest, "ids is required")
+		return
+	case len(ids) > maxBatchGetIDs:
+		logging.LogError("Too many ids for batch get", nil, logrus.Fields{"method": "BatchGet", "count": len(ids)})
+		writeProblem(w, r, probBadRequest, fmt.Sprintf("too many ids (at most %d)", maxBatchGetIDs))
+		return
+	}
+
+	found, missing, err := h.svc.GetOrders(r.Context(), ids)
+	if err != nil {
+		logging.LogError("Error fetching orders", err, logrus.Fields{"method": "BatchGet", "count": len(ids)})
+		writeErr(w, r, err)
+		return
+	}
+
+	logging.LogInfo("Orders fetched", logrus.Fields{"method": "BatchGet", "found": len(found), "missing": len(missing)})
+	writeJSON(w, http.StatusOK, BatchGetResponse{Orders: ToResponseList(found), Missing: missing})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// batchGetSvc serves GetOrders from a fixed set and records the ids asked for.
+type batchGetSvc struct {
+	serviceInterface
+	known map[string]order.Order
+	asked []string
+}
+
+func (s *batchGetSvc) GetOrders(_ context.Context, ids []string) ([]order.Order, []string, error) {
+	s.asked = ids
+	found, missing := []order.Order{}, []string{}
+	for _, id := range ids {
+		if o, ok := s.known[id]; ok {
+			found = append(found, o)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing, nil
+}
+
+func batchGet(t *testing.T, svc serviceInterface, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	logging.InitLogger()
+	w := httptest.NewRecorder()
+	h := NewOrderHandlers(svc, BulkLimits{})
+	if r.Method == http.MethodGet {
+		h.ListByIDsHandler(w, r)
+	} else {
+		h.BatchGetHandler(w, r)
+	}
+	return w
+}
+
+func TestBatchGet(t *testing.T) {
+	svc := &batchGetSvc{known: map[string]order.Order{"a": {OrderUID: "a"}, "c": {OrderUID: "c"}}}
+	body := `{"ids": ["c", " x ", "", "a"]}`
+	w := batchGet(t, svc, httptest.NewRequest(http.MethodPost, "/orders/batch-get", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp BatchGetResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"c", "x", "a"}, svc.asked)
+	require.Len(t, resp.Orders, 2)
+	assert.Equal(t, "c", resp.Orders[0].OrderUID)
+	assert.Equal(t, "a", resp.Orders[1].OrderUID)
+	assert.Equal(t, []string{"x"}, resp.Missing)
+}
+
+func TestBatchGetByQuery(t *testing.T) {
+	svc := &batchGetSvc{known: map[string]order.Order{"a": {OrderUID: "a"}}}
+	w := batchGet(t, svc, httptest.NewRequest(http.MethodGet, "/orders?ids=a,b&ids=c", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"a", "b", "c"}, svc.asked)
+	assert.JSONEq(t, `["b","c"]`, string(mustField(t, w.Body.Bytes(), "missing")))
+}
+
+func TestBatchGetRejectsBadIDLists(t *testing.T) {
+	tooMany := strings.TrimSuffix(strings.Repeat(`"id",`, maxBatchGetIDs+1), ",")
+	for name, r := range map[string]*http.Request{
+		"empty body list": httptest.NewRequest(http.MethodPost, "/orders/batch-get", strings.NewReader(`{"ids": [" "]}`)),
+		"no query ids":    httptest.NewRequest(http.MethodGet, "/orders", nil),
+		"too many ids":    httptest.NewRequest(http.MethodPost, "/orders/batch-get", strings.NewReader(`{"ids": [`+tooMany+`]}`)),
+		"malformed body":  httptest.NewRequest(http.MethodPost, "/orders/batch-get", strings.NewReader(`{"ids": "a"}`)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := &batchGetSvc{}
+			w := batchGet(t, svc, r)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			assert.Nil(t, svc.asked)
+		})
+	}
+}
+
+func mustField(t *testing.T, body []byte, key string) json.RawMessage {
+	t.Helper()
+	var m map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &m))
+	return m[key]
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/adapters/orderdto"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const ndjsonType = "application/x-ndjson"
+
+// BulkLimits bounds POST /orders/bulk. Records are written BatchSize at a
+// time; a request may carry at most MaxRecords records and MaxBytes bytes,
+// and may take Timeout to be read and answered.
+type BulkLimits struct {
+	BatchSize  int
+	MaxRecords int
+	MaxBytes   int64
+	Timeout    time.Duration
+}
+
+const (
+	BulkCreated = "created"
+	BulkUpdated = "updated"
+	BulkFailed  = "failed"
+	// BulkAborted marks a valid record of an all-or-nothing import that was
+	// not stored because another record failed.
+	BulkAborted = "aborted"
+)
+
+type BulkImportResponse struct {
+	Atomic  bool               `json:"atomic"`
+	Created int                `json:"created"`
+	Updated int                `json:"updated"`
+	Failed  int                `json:"failed"`
+	Aborted int                `json:"aborted"`
+	Results []BulkRecordResult `json:"results"`
+}
+
+// BulkRecordResult reports one record; Index is its position in the request.
+type BulkRecordResult struct {
+	Index    int      `json:"index"`
+	OrderUID string   `json:"order_uid,omitempty"`
+	Status   string   `json:"status"`
+	Version  int64    `json:"version,omitempty"`
+	Error    *Problem `json:"error,omitempty"`
+}
+
+// BulkImportHandler creates or updates the orders of a JSON array (the
+// default) or of NDJSON (Content-Type application/x-ndjson) of
+// orderdto.OrderUpsertRequest and reports every record. With atomic=true
+// nothing is stored unless every record is, and the response is 422 if one
+// fails.
+func (h *OrderHandlers) BulkImportHandler(w http.ResponseWriter, r *http.Request) {
+	ndjson := false
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		switch {
+		case err != nil:
+			writeProblem(w, r, probUnsupportedMedia, "invalid Content-Type")
+			return
+		case mt == ndjsonType:
+			ndjson = true
+		case mt != "application/json":
+			writeProblem(w, r, probUnsupportedMedia, "Content-Type must be application/json or "+ndjsonType)
+			return
+		}
+	}
+	atomic := false
+	if s := r.URL.Query().Get("atomic"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			logging.LogError("Invalid 'atomic' query parameter", err, logrus.Fields{"method": "BulkImport"})
+			writeProblem(w, r, probBadRequest, "invalid atomic (bool expected)")
+			return
+		}
+		atomic = v
+	}
+
+	if h.bulk.Timeout > 0 {
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Now().Add(h.bulk.Timeout))
+		_ = rc.SetWriteDeadline(time.Now().Add(h.bulk.Timeout))
+	}
+	if h.bulk.MaxBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.bulk.MaxBytes)
+	}
+	defer r.Body.Close()
+
+	decode := decodeBulkArray
+	if ndjson {
+		decode = decodeBulkNDJSON
+	}
+	reqs, err := decode(r.Body, h.bulk.MaxRecords)
+	if err != nil {
+		logging.LogError("Error decoding bulk request body", err, logrus.Fields{"method": "BulkImport"})
+		if errors.Is(err, errTooManyRecords) {
+			writeProblem(w, r, probBodyTooLarge, err.Error())
+			return
+		}
+		writeBodyErr(w, r, err)
+		return
+	}
+
+	resp := BulkImportResponse{Atomic: atomic, Results: make([]BulkRecordResult, len(reqs))}
+	valid := make([]order.Order, 0, len(reqs))
+	pos := make([]int, 0, len(reqs))
+	for i, req := range reqs {
+		resp.Results[i].Index = i
+		if req.err != nil {
+			resp.Results[i].Error = recordProblem(probMalformedBody, req.err.Error(), nil)
+			continue
+		}
+		if req.OrderUID != nil {
+			resp.Results[i].OrderUID = *req.OrderUID
+		}
+		o, err := req.ToModel()
+		if err != nil {
+			resp.Results[i].Error = recordProblem(probBadRequest, err.Error(), nil)
+			continue
+		}
+		if err := validation.IsValidOrder(o); err != nil {
+			resp.Results[i].Error = recordProblem(classifyErr(err))
+			continue
+		}
+		valid = append(valid, o)
+		pos = append(pos, i)
+	}
+
+	if atomic && len(valid) < len(reqs) {
+		for _, i := range pos {
+			resp.Results[i].Error = recordProblem(classifyErr(orders.ErrBulkAborted))
+		}
+	} else if len(valid) > 0 {
+		results, err := h.svc.ImportOrders(r.Context(), valid, orders.BulkOptions{BatchSize: h.bulk.BatchSize, Atomic: atomic})
+		if err != nil && (atomic || results == nil) {
+			logging.LogError("Error importing orders", err, logrus.Fields{"method": "BulkImport", "count": len(valid)})
+			writeErr(w, r, err)
+			return
+		}
+		for k, res := range results {
+			out := &resp.Results[pos[k]]
+			if res.Err != nil {
+				out.Error = recordProblem(classifyErr(res.Err))
+				continue
+			}
+			out.OrderUID, out.Version, out.Status = res.Order.OrderUID, res.Order.Version, BulkUpdated
+			if res.Created {
+				out.Status = BulkCreated
+			}
+		}
+	}
+
+	for i := range resp.Results {
+		res := &resp.Results[i]
+		switch {
+		case res.Status == BulkCreated:
+			resp.Created++
+		case res.Status == BulkUpdated:
+			resp.Updated++
+		case res.Error != nil && res.Error.Type == problemTypePrefix+probBulkAborted.slug:
+			res.Status = BulkAborted
+			resp.Aborted++
+		default:
+			res.Status = BulkFailed
+			resp.Failed++
+		}
+	}
+
+	logging.LogInfo("Bulk import processed", logrus.Fields{
+		"method": "BulkImport", "atomic": atomic, "records": len(reqs),
+		"created": resp.Created, "updated": resp.Updated, "failed": resp.Failed, "aborted": resp.Aborted,
+	})
+	status := http.StatusOK
+	if atomic && resp.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, resp)
+}
+
+// recordProblem is the problem reported for one record of a bulk import.
+func recordProblem(kind problemKind, detail string, fields []FieldProblem) *Problem {
+	return &Problem{
+		Type:   problemTypePrefix + kind.slug,
+		Title:  kind.title,
+		Status: kind.status,
+		Detail: detail,
+		Errors: fields,
+	}
+}
+
+var errTooManyRecords = errors.New("too many records")
+
+// bulkRequest is a decoded record, or the reason it could not be decoded.
+type bulkRequest struct {
+	orderdto.OrderUpsertRequest
+	err error
+}
+
+// decodeBulkArray reads a JSON array of records. A record of the wrong shape
+// fails alone; broken JSON fails the whole body.
+func decodeBulkArray(body io.Reader, max int) ([]bulkRequest, error) {
+	dec := json.NewDecoder(body)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, errors.New("request body must be a JSON array")
+	}
+	var out []bulkRequest
+	for dec.More() {
+		if max > 0 && len(out) == max {
+			return nil, fmt.Errorf("%w: at most %d per request", errTooManyRecords, max)
+		}
+		var req bulkRequest
+		if err := dec.Decode(&req.OrderUpsertRequest); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &typeErr) {
+				return nil, err
+			}
+			req = bulkRequest{err: err}
+		}
+		out = append(out, req)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeBulkNDJSON reads one record per line, skipping blank lines. Every
+// line is decoded on its own, so a broken line fails only its record.
+func decodeBulkNDJSON(body io.Reader, max int) ([]bulkRequest, error) {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	var out []bulkRequest
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if max > 0 && len(out) == max {
+			return nil, fmt.Errorf("%w: at most %d per request", errTooManyRecords, max)
+		}
+		var req bulkRequest
+		if err := json.Unmarshal(line, &req.OrderUpsertRequest); err != nil {
+			req = bulkRequest{err: err}
+		}
+		out = append(out, req)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/adapters/orderdto"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// bulkSvc stores every order it is given; uids in conflict fail with
+// orders.ErrConflict. Any other method panics.
+type bulkSvc struct {
+	serviceInterface
+	conflict map[string]bool
+	got      []order.Order
+	opts     orders.BulkOptions
+}
+
+func (s *bulkSvc) ImportOrders(_ context.Context, list []order.Order, opts orders.BulkOptions) ([]orders.BulkResult, error) {
+	s.got, s.opts = list, opts
+	out := make([]orders.BulkResult, len(list))
+	for i, o := range list {
+		if s.conflict[o.OrderUID] {
+			out[i].Err = orders.ErrConflict
+			continue
+		}
+		o.Version = 1
+		out[i] = orders.BulkResult{Order: o, Created: o.OrderUID == ""}
+	}
+	return out, nil
+}
+
+func bulkRecord(t *testing.T, uid string) string {
+	t.Helper()
+	now := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	req := orderdto.OrderUpsertRequest{
+		TrackNumber: "WBILMTESTTRACK", Entry: "WBIL", Locale: "en", CustomerID: "test", DeliveryService: "meest",
+		DateCreatedRFC: now,
+		Delivery: orderdto.DeliveryDTO{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+		Payment: orderdto.PaymentDTO{Transaction: "tx-" + uid, Currency: "USD", Provider: "wbpay", Amount: 1817,
+			PaymentDtRFC: now, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []orderdto.ItemDTO{{ChrtID: "9934930", TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "rid", Name: "Mascaras",
+			Sale: 30, TotalPrice: 317, NmID: "2389212", Brand: "Vivienne Sabo"}},
+	}
+	if uid != "" {
+		req.OrderUID = &uid
+	}
+	b, err := json.Marshal(req)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func bulkImport(t *testing.T, svc serviceInterface, limits BulkLimits, query, contentType, body string) (*httptest.ResponseRecorder, BulkImportResponse) {
+	t.Helper()
+	logging.InitLogger()
+	r := httptest.NewRequest(http.MethodPost, "/orders/bulk?"+query, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	NewOrderHandlers(svc, limits).BulkImportHandler(w, r)
+
+	var resp BulkImportResponse
+	if w.Header().Get("Content-Type") != problemContentType {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	}
+	return w, resp
+}
+
+func TestBulkImportArrayReportsEveryRecord(t *testing.T) {
+	svc := &bulkSvc{conflict: map[string]bool{"taken": true}}
+	body := "[" + strings.Join([]string{
+		bulkRecord(t, ""),
+		bulkRecord(t, "known"),
+		`{"track_number": "T", "sm_id": "not a number"}`,
+		strings.Replace(bulkRecord(t, "bad"), `"currency":"USD"`, `"currency":"ZZZ"`, 1),
+		bulkRecord(t, "taken"),
+	}, ",") + "]"
+
+	w, resp := bulkImport(t, svc, BulkLimits{BatchSize: 50}, "", "application/json", body)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 50, svc.opts.BatchSize)
+	assert.Len(t, svc.got, 3)
+	assert.Equal(t, []int{1, 1, 3, 0}, []int{resp.Created, resp.Updated, resp.Failed, resp.Aborted})
+	require.Len(t, resp.Results, 5)
+	assert.Equal(t, BulkCreated, resp.Results[0].Status)
+	assert.Equal(t, BulkUpdated, resp.Results[1].Status)
+	assert.Equal(t, int64(1), resp.Results[1].Version)
+	assert.Equal(t, "/problems/malformed-body", resp.Results[2].Error.Type)
+	assert.Equal(t, "/problems/validation-failed", resp.Results[3].Error.Type)
+	assert.Equal(t, "payment.currency", resp.Results[3].Error.Errors[0].Path)
+	assert.Equal(t, "taken", resp.Results[4].OrderUID)
+	assert.Equal(t, "/problems/conflict", resp.Results[4].Error.Type)
+}
+
+func TestBulkImportNDJSON(t *testing.T) {
+	svc := &bulkSvc{}
+	body := bulkRecord(t, "a") + "\n\n{broken\n" + bulkRecord(t, "b") + "\n"
+
+	w, resp := bulkImport(t, svc, BulkLimits{}, "", ndjsonType, body)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, resp.Results, 3)
+	assert.Equal(t, []string{BulkUpdated, BulkFailed, BulkUpdated},
+		[]string{resp.Results[0].Status, resp.Results[1].Status, resp.Results[2].Status})
+	assert.Equal(t, 1, resp.Results[1].Index)
+}
+
+func TestBulkImportAtomicStoresNothingOnInvalidRecord(t *testing.T) {
+	svc := &bulkSvc{}
+	body := "[" + bulkRecord(t, "a") + `,{"order_uid": "b"}]`
+
+	w, resp := bulkImport(t, svc, BulkLimits{}, "atomic=true", "", body)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Nil(t, svc.got)
+	assert.True(t, resp.Atomic)
+	assert.Equal(t, BulkAborted, resp.Results[0].Status)
+	assert.Equal(t, "/problems/bulk-aborted", resp.Results[0].Error.Type)
+	assert.Equal(t, BulkFailed, resp.Results[1].Status)
+	assert.Equal(t, "b", resp.Results[1].OrderUID)
+}
+
+func TestBulkImportRejectsRequest(t *testing.T) {
+	cases := []struct {
+		name, contentType, body string
+		limits                  BulkLimits
+		status                  int
+	}{
+		{"unsupported type", "text/csv", "a,b", BulkLimits{}, http.StatusUnsupportedMediaType},
+		{"not an array", "", `{"order_uid": "a"}`, BulkLimits{}, http.StatusBadRequest},
+		{"broken array", "", `[{"order_uid": }]`, BulkLimits{}, http.StatusBadRequest},
+		{"too many records", "", `[{}, {}, {}]`, BulkLimits{MaxRecords: 2}, http.StatusRequestEntityTooLarge},
+		{"too many bytes", ndjsonType, strings.Repeat("{}\n", 100), BulkLimits{MaxBytes: 64}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, _ := bulkImport(t, &bulkSvc{}, tc.limits, "", tc.contentType, tc.body)
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+type InconsistentOrderResponse struct {
+	OrderUID string                `json:"order_uid"`
+	Issues   []order.Inconsistency `json:"issues"`
+}
+
+type ConsistencyReportResponse struct {
+	Orders     []InconsistentOrderResponse `json:"orders"`
+	NextCursor string                      `json:"next_cursor,omitempty"`
+}
+
+// ConsistencyReportHandler lists stored orders whose totals do not add up.
+func (h *OrderHandlers) ConsistencyReportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := 0
+	if s := q.Get("limit"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			logging.LogError("Invalid 'limit' query parameter", err, logrus.Fields{"method": "ConsistencyReportHandler"})
+			writeProblem(w, r, probBadRequest, "invalid limit (non-negative integer expected)")
+			return
+		}
+		limit = v
+	}
+
+	report, err := h.svc.ConsistencyReport(r.Context(), q.Get("cursor"), limit)
+	if err != nil {
+		logging.LogError("Error building consistency report", err, logrus.Fields{"method": "ConsistencyReportHandler"})
+		writeErr(w, r, err)
+		return
+	}
+
+	out := ConsistencyReportResponse{
+		Orders:     make([]InconsistentOrderResponse, 0, len(report.Orders)),
+		NextCursor: report.NextCursor,
+	}
+	for _, o := range report.Orders {
+		out.Orders = append(out.Orders, InconsistentOrderResponse{OrderUID: o.OrderUID, Issues: o.Issues})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -2,7 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
-	"github.com/reybrally/order-service/internal/adapters/http/handlers/validation"
+	"errors"
+	"github.com/reybrally/order-service/internal/adapters/orderdto"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
 	"github.com/reybrally/order-service/internal/logging"
 	"github.com/sirupsen/logrus"
 	"net/http"
@@ -12,30 +15,41 @@ func (h *OrderHandlers) CreateOrUpdateOrder(w http.ResponseWriter, r *http.Reque
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
 	defer r.Body.Close()
 
-	var req OrderUpsertRequest
+	var req orderdto.OrderUpsertRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logging.LogError("Error decoding request body", err, logrus.Fields{"method": "CreateOrUpdateOrder"})
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeBodyErr(w, r, err)
 		return
 	}
 	logging.LogInfo("Request body decoded", logrus.Fields{"method": "CreateOrUpdateOrder"})
 	order, err := req.ToModel()
 	if err != nil {
 		logging.LogError("Error converting to model", err, logrus.Fields{"method": "CreateOrUpdateOrder"})
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeProblem(w, r, probBadRequest, err.Error())
 		return
 	}
 	if err := validation.IsValidOrder(order); err != nil {
 		logging.LogError("Invalid order", err, logrus.Fields{"method": "CreateOrUpdateOrder"})
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeErr(w, r, err)
 		return
 	}
+	version, conditional, err := ifMatchVersion(r)
+	if err != nil {
+		logging.LogError("Invalid If-Match header", err, logrus.Fields{"method": "CreateOrUpdateOrder"})
+		writeIfMatchErr(w, r, err)
+		return
+	}
+	order.Version = version
 	ctx := r.Context()
 
 	ord, err := h.svc.CreateOrUpdateOrder(ctx, order)
 	if err != nil {
 		logging.LogError("Error creating or updating order", err, logrus.Fields{"method": "CreateOrUpdateOrder"})
-		writeError(w, http.StatusInternalServerError, err.Error())
+		if conditional && errors.Is(err, orders.ErrConflict) {
+			writeProblem(w, r, probPreconditionFailed, "order version does not match If-Match")
+			return
+		}
+		writeErr(w, r, err)
 		return
 	}
 	status := http.StatusOK
@@ -45,6 +59,7 @@ func (h *OrderHandlers) CreateOrUpdateOrder(w http.ResponseWriter, r *http.Reque
 	}
 
 	logging.LogInfo("Order created or updated", logrus.Fields{"method": "CreateOrUpdateOrder", "order_uid": ord.OrderUID})
+	w.Header().Set("ETag", etag(ord.Version))
 	writeJSON(w, status, ToResponse(ord))
 
 }
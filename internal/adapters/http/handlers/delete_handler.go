@@ -13,7 +13,7 @@ func (h *OrderHandlers) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
 		logging.LogError("ID is required in DeleteHandler", nil, logrus.Fields{"method": "DeleteHandler", "id": id})
-		writeError(w, http.StatusBadRequest, "id can't be empty")
+		writeProblem(w, r, probBadRequest, "id can't be empty")
 		return
 	}
 
@@ -23,11 +23,11 @@ func (h *OrderHandlers) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, orders.ErrNotFound) {
 			logging.LogError("Order not found", err, logrus.Fields{"method": "DeleteHandler", "id": id})
-			writeError(w, http.StatusNotFound, "order not found")
+			writeProblem(w, r, probNotFound, "order not found")
 			return
 		}
 		logging.LogError("Error deleting order", err, logrus.Fields{"method": "DeleteHandler", "id": id})
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeErr(w, r, err)
 		return
 	}
 
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/reybrally/order-service/internal/domain/order"
+)
+
+var (
+	errBadIfMatch  = errors.New(`invalid If-Match header (want "*" or a single "<version>" entity tag)`)
+	errWeakIfMatch = errors.New("weak entity tags never match If-Match, which compares strongly")
+)
+
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion returns the order version from the If-Match header and
+// whether the write is conditional. An absent header means an unconditional
+// write; the wildcard requires the order to exist and yields
+// order.AnyVersion. Weak tags fail with errWeakIfMatch, as If-Match only
+// matches strongly (RFC 9110, section 13.1.1).
+func ifMatchVersion(r *http.Request) (int64, bool, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case raw == "":
+		return 0, false, nil
+	case raw == "*":
+		return order.AnyVersion, true, nil
+	case strings.HasPrefix(raw, "W/"):
+		return 0, false, errWeakIfMatch
+	}
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return 0, false, errBadIfMatch
+	}
+	v, err := strconv.ParseInt(raw[1:len(raw)-1], 10, 64)
+	if err != nil || v <= 0 {
+		return 0, false, errBadIfMatch
+	}
+	return v, true, nil
+}
+
+// writeIfMatchErr answers an If-Match header that ifMatchVersion rejected:
+// 412 for a weak tag, which cannot match, and 400 for a malformed one.
+func writeIfMatchErr(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errWeakIfMatch) {
+		writeProblem(w, r, probPreconditionFailed, err.Error())
+		return
+	}
+	writeProblem(w, r, probBadRequest, err.Error())
+}
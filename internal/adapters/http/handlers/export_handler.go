@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/adapters/http/handlers/normalization"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	// exportFlushEvery is how many orders are written between flushes.
+	exportFlushEvery = 200
+	// exportWriteTimeout is how long the client may take to read one flushed
+	// chunk; the server-wide write timeout does not apply to exports.
+	exportWriteTimeout = 30 * time.Second
+)
+
+// ExportHandler streams every order matching the search filters as NDJSON of
+// OrderResponse (format=ndjson, the default) or as CSV with one row per order
+// or, with rows=item, per item. The route must not be wrapped in a request
+// timeout: the export runs for as long as the client keeps reading.
+func (h *OrderHandlers) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, ok := parseSearchFilters(w, r, "ExportOrders")
+	if !ok {
+		return
+	}
+
+	var enc exportEncoder
+	switch format := q.Get("format"); format {
+	case "", "ndjson":
+		enc = &ndjsonEncoder{enc: json.NewEncoder(w)}
+	case "csv":
+		switch rows := q.Get("rows"); rows {
+		case "", "order":
+			enc = &csvEncoder{w: csv.NewWriter(w)}
+		case "item":
+			enc = &csvEncoder{w: csv.NewWriter(w), perItem: true}
+		default:
+			logging.LogError("Invalid 'rows' query parameter", nil, logrus.Fields{"method": "ExportOrders", "rows": rows})
+			writeProblem(w, r, probBadRequest, "invalid rows (order or item expected)")
+			return
+		}
+	default:
+		logging.LogError("Invalid 'format' query parameter", nil, logrus.Fields{"method": "ExportOrders", "format": format})
+		writeProblem(w, r, probBadRequest, "invalid format (ndjson or csv expected)")
+		return
+	}
+
+	p := orders.PageRequest{SortBy: q.Get("sort_by"), SortDir: q.Get("sort_dir")}
+	if !checkSort(w, r, "ExportOrders", f, p.SortBy) {
+		return
+	}
+	normalization.NormalizeRequest(&p)
+
+	s := &exportStream{w: w, rc: http.NewResponseController(w), enc: enc}
+	err := h.svc.ExportOrders(r.Context(), f, p.SortBy, p.SortDir, s.write)
+	if err == nil {
+		err = s.finish()
+	}
+	switch {
+	case err == nil:
+		logging.LogInfo("Orders exported", logrus.Fields{"method": "ExportOrders", "count": s.count, "format": enc.contentType()})
+	case !s.started:
+		logging.LogError("Error exporting orders", err, logrus.Fields{"method": "ExportOrders"})
+		writeErr(w, r, err)
+	case r.Context().Err() != nil || errors.Is(err, context.Canceled):
+		logging.LogInfo("Order export cancelled by client", logrus.Fields{"method": "ExportOrders", "count": s.count})
+	default:
+		// The status line is gone; dropping the connection is the only way to
+		// tell the client that the export is incomplete.
+		logging.LogError("Order export failed mid-stream", err, logrus.Fields{"method": "ExportOrders", "count": s.count})
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportStream writes the response headers on the first order, so that an
+// error before it can still be reported as a problem.
+type exportStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	enc     exportEncoder
+	started bool
+	count   int
+}
+
+func (s *exportStream) start() error {
+	s.started = true
+	s.w.Header().Set("Content-Type", s.enc.contentType())
+	s.w.Header().Set("Content-Disposition", `attachment; filename="orders.`+s.enc.extension()+`"`)
+	_ = s.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	s.w.WriteHeader(http.StatusOK)
+	return s.enc.header()
+}
+
+func (s *exportStream) write(o order.Order) error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	if err := s.enc.encode(ToResponse(o)); err != nil {
+		return err
+	}
+	s.count++
+	if s.count%exportFlushEvery == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *exportStream) flush() error {
+	if err := s.enc.flush(); err != nil {
+		return err
+	}
+	_ = s.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func (s *exportStream) finish() error {
+	if !s.started {
+		if err := s.start(); err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+type exportEncoder interface {
+	contentType() string
+	extension() string
+	header() error
+	encode(o OrderResponse) error
+	flush() error
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) contentType() string          { return "application/x-ndjson" }
+func (e *ndjsonEncoder) extension() string            { return "ndjson" }
+func (e *ndjsonEncoder) header() error                { return nil }
+func (e *ndjsonEncoder) encode(o OrderResponse) error { return e.enc.Encode(o) }
+func (e *ndjsonEncoder) flush() error                 { return nil }
+
+// csvEncoder flattens an order into the columns of csvOrderColumns followed,
+// per order, by items_count or, per item, by csvItemColumns. An order without
+// items still yields one row with empty item columns.
+type csvEncoder struct {
+	w       *csv.Writer
+	perItem bool
+}
+
+func (e *csvEncoder) contentType() string { return "text/csv; charset=utf-8" }
+func (e *csvEncoder) extension() string   { return "csv" }
+
+func (e *csvEncoder) header() error {
+	rec := make([]string, 0, len(csvOrderColumns)+len(csvItemColumns))
+	for _, c := range csvOrderColumns {
+		rec = append(rec, c.name)
+	}
+	if !e.perItem {
+		return e.w.Write(append(rec, "items_count"))
+	}
+	for _, c := range csvItemColumns {
+		rec = append(rec, "item_"+c.name)
+	}
+	return e.w.Write(rec)
+}
+
+func (e *csvEncoder) encode(o OrderResponse) error {
+	rec := make([]string, 0, len(csvOrderColumns)+len(csvItemColumns))
+	for _, c := range csvOrderColumns {
+		rec = append(rec, c.value(o))
+	}
+	if !e.perItem {
+		return e.w.Write(append(rec, strconv.Itoa(len(o.Items))))
+	}
+	if len(o.Items) == 0 {
+		return e.w.Write(append(rec, make([]string, len(csvItemColumns))...))
+	}
+	n := len(rec)
+	for _, it := range o.Items {
+		rec = rec[:n]
+		for _, c := range csvItemColumns {
+			rec = append(rec, c.value(it))
+		}
+		if err := e.w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func itoa(v int64) string { return strconv.FormatInt(v, 10) }
+
+var csvOrderColumns = []struct {
+	name  string
+	value func(OrderResponse) string
+}{
+	{"order_uid", func(o OrderResponse) string { return o.OrderUID }},
+	{"track_number", func(o OrderResponse) string { return o.TrackNumber }},
+	{"entry", func(o OrderResponse) string { return o.Entry }},
+	{"locale", func(o OrderResponse) string { return o.Locale }},
+	{"customer_id", func(o OrderResponse) string { return o.CustomerID }},
+	{"delivery_service", func(o OrderResponse) string { return o.DeliveryService }},
+	{"status", func(o OrderResponse) string { return o.Status }},
+	{"version", func(o OrderResponse) string { return itoa(o.Version) }},
+	{"date_created", func(o OrderResponse) string { return o.DateCreated }},
+	{"deleted_at", func(o OrderResponse) string { return o.DeletedAt }},
+	{"delivery_name", func(o OrderResponse) string { return o.Delivery.Name }},
+	{"delivery_phone", func(o OrderResponse) string { return o.Delivery.Phone }},
+	{"delivery_zip", func(o OrderResponse) string { return o.Delivery.Zip }},
+	{"delivery_city", func(o OrderResponse) string { return o.Delivery.City }},
+	{"delivery_address", func(o OrderResponse) string { return o.Delivery.Address }},
+	{"delivery_region", func(o OrderResponse) string { return o.Delivery.Region }},
+	{"delivery_email", func(o OrderResponse) string { return o.Delivery.Email }},
+	{"payment_transaction", func(o OrderResponse) string { return o.Payment.Transaction }},
+	{"payment_currency", func(o OrderResponse) string { return o.Payment.Currency }},
+	{"payment_provider", func(o OrderResponse) string { return o.Payment.Provider }},
+	{"payment_amount", func(o OrderResponse) string { return itoa(o.Payment.Amount) }},
+	{"payment_amount_decimal", func(o OrderResponse) string { return o.Payment.AmountDecimal }},
+	{"payment_dt", func(o OrderResponse) string { return o.Payment.PaymentDt }},
+	{"payment_bank", func(o OrderResponse) string { return o.Payment.Bank }},
+	{"payment_delivery_cost", func(o OrderResponse) string { return itoa(o.Payment.DeliveryCost) }},
+	{"payment_goods_total", func(o OrderResponse) string { return itoa(o.Payment.GoodsTotal) }},
+	{"payment_custom_fee", func(o OrderResponse) string { return itoa(o.Payment.CustomFee) }},
+}
+
+var csvItemColumns = []struct {
+	name  string
+	value func(ItemResponse) string
+}{
+	{"chrt_id", func(it ItemResponse) string { return it.ChrtID }},
+	{"nm_id", func(it ItemResponse) string { return it.NmID }},
+	{"rid", func(it ItemResponse) string { return it.Rid }},
+	{"name", func(it ItemResponse) string { return it.Name }},
+	{"brand", func(it ItemResponse) string { return it.Brand }},
+	{"size", func(it ItemResponse) string { return itoa(it.Size) }},
+	{"price", func(it ItemResponse) string { return itoa(it.Price) }},
+	{"sale", func(it ItemResponse) string { return itoa(it.Sale) }},
+	{"total_price", func(it ItemResponse) string { return itoa(it.TotalPrice) }},
+	{"total_price_decimal", func(it ItemResponse) string { return it.TotalPriceDecimal }},
+	{"status", func(it ItemResponse) string { return itoa(it.Status) }},
+}
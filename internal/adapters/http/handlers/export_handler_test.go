@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// exportSvc serves ExportOrders from a fixed list; any other method panics.
+type exportSvc struct {
+	serviceInterface
+	list []order.Order
+	err  error
+}
+
+func (s *exportSvc) ExportOrders(_ context.Context, _ orders.SearchFilters, _, _ string, fn func(order.Order) error) error {
+	if s.err != nil {
+		return s.err
+	}
+	for _, o := range s.list {
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportOrders() []order.Order {
+	item := order.Item{ChrtId: "ch1", NmId: "nm-1", Name: "Mascaras", Brand: "Vivienne Sabo", Price: 1000, TotalPrice: 1000}
+	second := item
+	second.ChrtId, second.Name = "ch2", `Lip "gloss", red`
+	return []order.Order{
+		{OrderUID: "a", DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+			Payment: order.Payment{Currency: "USD", Amount: 2000}, Items: []order.Item{item, second}},
+		{OrderUID: "b", DateCreated: time.Date(2021, 11, 27, 6, 22, 19, 0, time.UTC)},
+	}
+}
+
+func export(t *testing.T, svc serviceInterface, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	logging.InitLogger()
+	w := httptest.NewRecorder()
+	NewOrderHandlers(svc, BulkLimits{}).ExportHandler(w, httptest.NewRequest(http.MethodGet, "/orders/export?"+query, nil))
+	return w
+}
+
+func TestExportNDJSON(t *testing.T) {
+	w := export(t, &exportSvc{list: exportOrders()}, "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	var got OrderResponse
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &got))
+	assert.Equal(t, "a", got.OrderUID)
+	assert.Equal(t, "20.00", got.Payment.AmountDecimal)
+	assert.Len(t, got.Items, 2)
+}
+
+func TestExportCSVPerOrderAndPerItem(t *testing.T) {
+	w := export(t, &exportSvc{list: exportOrders()}, "format=csv")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="orders.csv"`, w.Header().Get("Content-Disposition"))
+	recs, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	assert.Equal(t, "order_uid", recs[0][0])
+	assert.Equal(t, "items_count", recs[0][len(recs[0])-1])
+	assert.Equal(t, []string{"a", "2"}, []string{recs[1][0], recs[1][len(recs[1])-1]})
+
+	w = export(t, &exportSvc{list: exportOrders()}, "format=csv&rows=item")
+	require.Equal(t, http.StatusOK, w.Code)
+	recs, err = csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	// Header, two items of a and one empty item row for b.
+	require.Len(t, recs, 4)
+	name := len(csvOrderColumns) + 3
+	assert.Equal(t, "item_name", recs[0][name])
+	assert.Equal(t, `Lip "gloss", red`, recs[2][name])
+	assert.Equal(t, []string{"b", ""}, []string{recs[3][0], recs[3][name]})
+}
+
+func TestExportEmptyWritesHeaderOnly(t *testing.T) {
+	w := export(t, &exportSvc{}, "format=csv")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	recs, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, recs, 1)
+}
+
+func TestExportErrors(t *testing.T) {
+	w := export(t, &exportSvc{err: orders.ErrTimeout}, "")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	for _, query := range []string{"format=xml", "format=csv&rows=brand", "sort_by=relevance", "sort_by=relevance&q=+"} {
+		w := export(t, &exportSvc{}, query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestExportSortsByRelevanceWithQuery(t *testing.T) {
+	w := export(t, &exportSvc{list: exportOrders()}, "sort_by=relevance&q=sabo")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
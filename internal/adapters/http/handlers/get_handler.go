@@ -13,7 +13,7 @@ func (h *OrderHandlers) GetHandler(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
 		logging.LogError("ID is required in GetHandler", nil, logrus.Fields{"method": "GetHandler", "id": id})
-		writeError(w, http.StatusBadRequest, "id is required")
+		writeProblem(w, r, probBadRequest, "id is required")
 		return
 	}
 
@@ -24,14 +24,15 @@ func (h *OrderHandlers) GetHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, orders.ErrNotFound) {
 			logging.LogError("Order not found", err, logrus.Fields{"method": "GetHandler", "id": id})
-			writeError(w, http.StatusNotFound, "order not found")
+			writeProblem(w, r, probNotFound, "order not found")
 			return
 		}
 		logging.LogError("Internal server error while fetching order", err, logrus.Fields{"method": "GetHandler", "id": id})
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeErr(w, r, err)
 		return
 	}
 	logging.LogInfo("Order found", logrus.Fields{"method": "GetHandler", "id": id})
+	w.Header().Set("ETag", etag(order.Version))
 	writeJSON(w, http.StatusOK, ToResponse(order))
 
 }
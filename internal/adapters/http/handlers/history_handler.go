@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+)
+
+type RevisionResponse struct {
+	Rev       int64          `json:"rev"`
+	Op        string         `json:"op"`
+	Actor     string         `json:"actor"`
+	RequestID string         `json:"request_id"`
+	CreatedAt string         `json:"created_at"`
+	Order     *OrderResponse `json:"order,omitempty"`
+}
+
+func toRevisionResponse(rev order.Revision) RevisionResponse {
+	out := RevisionResponse{
+		Rev:       rev.Rev,
+		Op:        string(rev.Op),
+		Actor:     rev.Actor,
+		RequestID: rev.RequestID,
+		CreatedAt: rev.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if rev.Snapshot != nil {
+		o := ToResponse(*rev.Snapshot)
+		out.Order = &o
+	}
+	return out
+}
+
+func (h *OrderHandlers) HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		logging.LogError("ID is required in HistoryHandler", nil, logrus.Fields{"method": "HistoryHandler"})
+		writeProblem(w, r, probBadRequest, "id is required")
+		return
+	}
+
+	list, err := h.svc.OrderHistory(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFound) {
+			writeProblem(w, r, probNotFound, "order history not found")
+			return
+		}
+		logging.LogError("Error fetching order history", err, logrus.Fields{"method": "HistoryHandler", "id": id})
+		writeErr(w, r, err)
+		return
+	}
+
+	out := make([]RevisionResponse, 0, len(list))
+	for _, rev := range list {
+		out = append(out, toRevisionResponse(rev))
+	}
+	logging.LogInfo("Order history found", logrus.Fields{"method": "HistoryHandler", "id": id, "count": len(out)})
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *OrderHandlers) RevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	rev, err := strconv.ParseInt(chi.URLParam(r, "rev"), 10, 64)
+	if id == "" || err != nil || rev <= 0 {
+		logging.LogError("Invalid revision request", err, logrus.Fields{"method": "RevisionHandler", "id": id})
+		writeProblem(w, r, probBadRequest, "id and positive numeric rev are required")
+		return
+	}
+
+	out, err := h.svc.OrderRevision(r.Context(), id, rev)
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFound) {
+			writeProblem(w, r, probNotFound, "revision not found")
+			return
+		}
+		logging.LogError("Error fetching order revision", err, logrus.Fields{"method": "RevisionHandler", "id": id, "rev": rev})
+		writeErr(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, toRevisionResponse(out))
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotentReplayed   = "Idempotent-Replayed"
+	maxIdempotencyKeyLen = 255
+	// idempotencySaveTimeout bounds storing or releasing a key after the
+	// request, whose own context may be done by then.
+	idempotencySaveTimeout = 2 * time.Second
+)
+
+// replayedHeaders are the response headers stored with a key and replayed.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// IdempotencyMiddleware makes a request carrying an Idempotency-Key safe to
+// retry. The first request with a key runs and its response is stored for
+// ttl; a retry with the same method, path, If-Match and body gets that
+// response back, a retry while it still runs gets 409, and reusing the key
+// for a different request gets 422. While the first request runs the key is
+// only held for lock, so a key left behind by a crashed process can be
+// retried once lock passes. Responses with a 5xx status are not stored, so a
+// failed request can be retried with the same key. Requests without the
+// header pass through.
+func IdempotencyMiddleware(store orders.IdempotencyStore, ttl, lock time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(idempotencyHeader))
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				logging.LogError("Idempotency key too long", nil, logrus.Fields{"length": len(key)})
+				writeProblem(w, r, probBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKeyLen)+" characters")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+			_ = r.Body.Close()
+			if err != nil {
+				logging.LogError("Error reading request body", err, logrus.Fields{"idempotency_key": key})
+				writeBodyErr(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fp := requestFingerprint(r, body)
+
+			rec, reserved, err := store.Reserve(r.Context(), key, fp, lock)
+			if err != nil {
+				logging.LogError("Error reserving idempotency key", err, logrus.Fields{"idempotency_key": key})
+				writeErr(w, r, err)
+				return
+			}
+			if !reserved {
+				switch {
+				case rec.Fingerprint != fp:
+					logging.LogWarn("Idempotency key reused with a different request", logrus.Fields{"idempotency_key": key})
+					writeProblem(w, r, probIdempotencyReused, "Idempotency-Key was already used for a different request")
+				case rec.Response == nil:
+					logging.LogWarn("Idempotency key in use by a request in flight", logrus.Fields{"idempotency_key": key})
+					writeProblem(w, r, probIdempotencyBusy, "the first request with this Idempotency-Key has not finished")
+				default:
+					logging.LogInfo("Replaying idempotent response", logrus.Fields{"idempotency_key": key, "status": rec.Response.Status})
+					replay(w, *rec.Response)
+				}
+				return
+			}
+
+			rw := &recordingWriter{ResponseWriter: w}
+			stored := false
+			defer func() {
+				if stored {
+					return
+				}
+				ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencySaveTimeout)
+				defer cancel()
+				_ = store.Release(ctx, key)
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			if rw.status() >= http.StatusInternalServerError {
+				return
+			}
+			resp := orders.IdempotentResponse{Status: rw.status(), Header: map[string]string{}, Body: rw.body.Bytes()}
+			for _, h := range replayedHeaders {
+				if v := rw.Header().Get(h); v != "" {
+					resp.Header[h] = v
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencySaveTimeout)
+			defer cancel()
+			stored = store.Complete(ctx, key, resp, ttl) == nil
+		})
+	}
+}
+
+// requestFingerprint identifies a request for an Idempotency-Key; a JSON body
+// is compacted first so that retries differing only in whitespace match.
+func requestFingerprint(r *http.Request, body []byte) string {
+	var compact bytes.Buffer
+	if json.Compact(&compact, body) == nil {
+		body = compact.Bytes()
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.Path+"\n"+r.Header.Get("If-Match")+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, resp orders.IdempotentResponse) {
+	for k, v := range resp.Header {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set(idempotentReplayed, "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
+// recordingWriter passes the response through and keeps a copy of it.
+type recordingWriter struct {
+	http.ResponseWriter
+	code int
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *recordingWriter) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
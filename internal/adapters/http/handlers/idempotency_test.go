@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// memIdempotencyStore keeps keys in memory and expires them against now,
+// which only moves when a test advances it.
+type memIdempotencyStore struct {
+	mu      sync.Mutex
+	now     time.Time
+	recs    map[string]orders.IdempotencyRecord
+	expires map[string]time.Time
+}
+
+func (s *memIdempotencyStore) Reserve(_ context.Context, key, fp string, lock time.Duration) (orders.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec, ok := s.recs[key]; ok && s.now.Before(s.expires[key]) {
+		return rec, false, nil
+	}
+	rec := orders.IdempotencyRecord{Key: key, Fingerprint: fp}
+	s.recs[key] = rec
+	s.expires[key] = s.now.Add(lock)
+	return rec, true, nil
+}
+
+func (s *memIdempotencyStore) Complete(_ context.Context, key string, resp orders.IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec := s.recs[key]
+	rec.Response = &resp
+	s.recs[key] = rec
+	s.expires[key] = s.now.Add(ttl)
+	return nil
+}
+
+func (s *memIdempotencyStore) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+func (s *memIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recs[key].Response == nil {
+		delete(s.recs, key)
+	}
+	return nil
+}
+
+func (s *memIdempotencyStore) PurgeExpired(context.Context) (int64, error) { return 0, nil }
+
+type idempotencyFixture struct {
+	store   *memIdempotencyStore
+	handler http.Handler
+	calls   int
+	status  int
+}
+
+func newIdempotencyFixture() *idempotencyFixture {
+	logging.InitLogger()
+	store := &memIdempotencyStore{
+		now:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		recs:    map[string]orders.IdempotencyRecord{},
+		expires: map[string]time.Time{},
+	}
+	f := &idempotencyFixture{store: store, status: http.StatusCreated}
+	f.handler = IdempotencyMiddleware(f.store, time.Hour, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.calls++
+		w.Header().Set("Location", "/orders/uid-"+strconv.Itoa(f.calls))
+		writeJSON(w, f.status, map[string]int{"call": f.calls})
+	}))
+	return f
+}
+
+func (f *idempotencyFixture) post(key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysIdenticalRetry(t *testing.T) {
+	f := newIdempotencyFixture()
+	first := f.post("k1", `{"entry": "WBIL"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	retry := f.post("k1", "{\n  \"entry\": \"WBIL\"\n}")
+	assert.Equal(t, 1, f.calls)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, first.Header().Get("Location"), retry.Header().Get("Location"))
+	assert.Equal(t, "true", retry.Header().Get(idempotentReplayed))
+	assert.Empty(t, first.Header().Get(idempotentReplayed))
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	f := newIdempotencyFixture()
+	f.post("k1", `{"entry": "WBIL"}`)
+
+	w := f.post("k1", `{"entry": "OTHER"}`)
+	assert.Equal(t, 1, f.calls)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "/problems/idempotency-key-reused")
+}
+
+func TestIdempotencyRequestInFlight(t *testing.T) {
+	f := newIdempotencyFixture()
+	_, _, _ = f.store.Reserve(context.Background(), "k1", requestFingerprint(httptest.NewRequest(http.MethodPost, "/orders", nil), []byte(`{}`)), time.Minute)
+
+	w := f.post("k1", `{}`)
+	assert.Equal(t, 0, f.calls)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func TestIdempotencyTakesOverStaleKey(t *testing.T) {
+	f := newIdempotencyFixture()
+	// The first request never finished, e.g. its process died.
+	_, _, _ = f.store.Reserve(context.Background(), "k1", requestFingerprint(httptest.NewRequest(http.MethodPost, "/orders", nil), []byte(`{}`)), time.Minute)
+
+	f.store.advance(time.Minute)
+	w := f.post("k1", `{}`)
+	assert.Equal(t, 1, f.calls)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// The stored response outlives the lock.
+	f.store.advance(30 * time.Minute)
+	w = f.post("k1", `{}`)
+	assert.Equal(t, 1, f.calls)
+	assert.Equal(t, "true", w.Header().Get(idempotentReplayed))
+
+	f.store.advance(30 * time.Minute)
+	f.post("k1", `{}`)
+	assert.Equal(t, 2, f.calls, "an expired response is not replayed")
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	f := newIdempotencyFixture()
+	f.status = http.StatusServiceUnavailable
+	require.Equal(t, http.StatusServiceUnavailable, f.post("k1", `{}`).Code)
+
+	f.status = http.StatusCreated
+	w := f.post("k1", `{}`)
+	assert.Equal(t, 2, f.calls)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(idempotentReplayed))
+}
+
+func TestIdempotencyWithoutKey(t *testing.T) {
+	f := newIdempotencyFixture()
+	f.post("", `{}`)
+	f.post("", `{}`)
+	assert.Equal(t, 2, f.calls)
+	assert.Empty(t, f.store.recs)
+
+	w := f.post(strings.Repeat("k", maxIdempotencyKeyLen+1), `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 2, f.calls)
+}
@@ -39,6 +39,20 @@ func NormalizeSearchFilters(f *orders.SearchFilters) {
 	f.Provider = normalizeString(f.Provider, func(s string) string { return strings.ToLower(s) })
 	f.Currency = normalizeString(f.Currency, func(s string) string { return strings.ToUpper(s) })
 
+	f.NmIDs = normalizeList(f.NmIDs)
+	f.Brands = normalizeList(f.Brands, strings.ToLower)
+	f.ChrtIDs = normalizeList(f.ChrtIDs)
+	f.Rids = normalizeList(f.Rids)
+	f.DeliveryServices = normalizeList(f.DeliveryServices)
+	f.Cities = normalizeList(f.Cities, strings.ToLower)
+	f.Regions = normalizeList(f.Regions, strings.ToLower)
+	f.Phones = normalizeList(f.Phones)
+	f.Emails = normalizeList(f.Emails, strings.ToLower)
+
+	if f.AmountMin != nil && f.AmountMax != nil && *f.AmountMin > *f.AmountMax {
+		f.AmountMin, f.AmountMax = f.AmountMax, f.AmountMin
+	}
+
 	if f.Query != nil {
 		q := strings.TrimSpace(*f.Query)
 		if len(q) < 2 {
@@ -59,7 +73,8 @@ func NormalizeRequest(p *orders.PageRequest) {
 	if p.SortBy == "" {
 		p.SortBy = "date_created"
 	}
-	if p.SortDir == "" {
+	p.SortDir = strings.ToLower(strings.TrimSpace(p.SortDir))
+	if p.SortDir != "asc" {
 		p.SortDir = "desc"
 	}
 }
@@ -84,3 +99,22 @@ func normalizeString(p *string, normFunc ...func(string) string) *string {
 
 	return &result
 }
+
+// normalizeList trims the values, drops empty ones and duplicates and returns
+// nil when nothing is left.
+func normalizeList(vals []string, normFunc ...func(string) string) []string {
+	var out []string
+	seen := make(map[string]struct{}, len(vals))
+	for _, v := range vals {
+		p := normalizeString(&v, normFunc...)
+		if p == nil {
+			continue
+		}
+		if _, ok := seen[*p]; ok {
+			continue
+		}
+		seen[*p] = struct{}{}
+		out = append(out, *p)
+	}
+	return out
+}
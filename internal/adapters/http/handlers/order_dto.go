@@ -1,155 +1,10 @@
 package handlers
 
 import (
-	"errors"
-	"time"
-
+	"github.com/reybrally/order-service/internal/app/orders"
 	"github.com/reybrally/order-service/internal/domain/order"
 )
 
-type OrderUpsertRequest struct {
-	OrderUID        *string `json:"order_uid,omitempty"`
-	TrackNumber     string  `json:"track_number"`
-	Entry           string  `json:"entry"`
-	Locale          string  `json:"locale"`
-	CustomerID      string  `json:"customer_id"`
-	DeliveryService string  `json:"delivery_service"`
-	ShardKey        string  `json:"shard_key"`
-	SmID            int64   `json:"sm_id"`
-	OofShard        int64   `json:"oof_shard"`
-
-	DateCreatedRFC string `json:"date_created,omitempty"`
-
-	Delivery DeliveryDTO `json:"delivery"`
-	Payment  PaymentDTO  `json:"payment"`
-	Items    []ItemDTO   `json:"items"`
-}
-
-type DeliveryDTO struct {
-	Name    string `json:"name"`
-	Phone   string `json:"phone"`
-	Zip     string `json:"zip"`
-	City    string `json:"city"`
-	Address string `json:"address"`
-	Region  string `json:"region"`
-	Email   string `json:"email"`
-}
-
-type PaymentDTO struct {
-	Transaction  string `json:"transaction"`
-	RequestID    string `json:"request_id"`
-	Currency     string `json:"currency"`
-	Provider     string `json:"provider"`
-	Amount       int64  `json:"amount"`
-	PaymentDtRFC string `json:"payment_dt"`
-	Bank         string `json:"bank"`
-	DeliveryCost int64  `json:"delivery_cost"`
-	GoodsTotal   int64  `json:"goods_total"`
-	CustomFee    int64  `json:"custom_fee"`
-}
-
-type ItemDTO struct {
-	ChrtID      string `json:"chrt_id"`
-	TrackNumber string `json:"track_number"`
-	Price       int64  `json:"price"`
-	Rid         string `json:"rid"`
-	Name        string `json:"item_name"`
-	Sale        int64  `json:"sale"`
-	Size        int64  `json:"item_size"`
-	TotalPrice  int64  `json:"total_price"`
-	NmID        string `json:"nm_id"`
-	Brand       string `json:"brand"`
-	Status      int64  `json:"status"`
-}
-
-func (r OrderUpsertRequest) ToModel() (order.Order, error) {
-	if r.TrackNumber == "" || r.Entry == "" || r.Locale == "" || r.CustomerID == "" || r.DeliveryService == "" {
-		return order.Order{}, errors.New("missing required fields")
-	}
-
-	var payTime time.Time
-	if r.Payment.PaymentDtRFC != "" {
-		t, err := time.Parse(time.RFC3339, r.Payment.PaymentDtRFC)
-		if err != nil {
-			return order.Order{}, errors.New("invalid payment_dt format (want RFC3339)")
-		}
-		payTime = t
-	}
-
-	var createdAt time.Time
-	if r.DateCreatedRFC != "" {
-		t, err := time.Parse(time.RFC3339, r.DateCreatedRFC)
-		if err != nil {
-			return order.Order{}, errors.New("invalid date_created (want RFC3339)")
-		}
-		createdAt = t
-	} else {
-		createdAt = time.Now().UTC()
-	}
-
-	out := order.Order{
-		OrderUID:          derefStr(r.OrderUID),
-		TrackNumber:       r.TrackNumber,
-		Entry:             r.Entry,
-		Locale:            r.Locale,
-		InternalSignature: "",
-		CustomerId:        r.CustomerID,
-		DeliveryService:   r.DeliveryService,
-		ShardKey:          r.ShardKey,
-		SmId:              r.SmID,
-		OofShard:          r.OofShard,
-
-		Delivery: order.Delivery{
-			Name:    r.Delivery.Name,
-			Phone:   r.Delivery.Phone,
-			Zip:     r.Delivery.Zip,
-			City:    r.Delivery.City,
-			Address: r.Delivery.Address,
-			Region:  r.Delivery.Region,
-			Email:   r.Delivery.Email,
-		},
-		Payment: order.Payment{
-			Transaction:  r.Payment.Transaction,
-			RequestId:    r.Payment.RequestID,
-			Currency:     r.Payment.Currency,
-			Provider:     r.Payment.Provider,
-			Amount:       r.Payment.Amount,
-			PaymentDt:    payTime,
-			Bank:         r.Payment.Bank,
-			DeliveryCost: r.Payment.DeliveryCost,
-			GoodsTotal:   r.Payment.GoodsTotal,
-			CustomFee:    r.Payment.CustomFee,
-		},
-		DateCreated: createdAt,
-		Items:       make([]order.Item, 0, len(r.Items)),
-	}
-
-	for _, it := range r.Items {
-		out.Items = append(out.Items, order.Item{
-			ChrtId:      it.ChrtID,
-			TrackNumber: it.TrackNumber,
-			Price:       it.Price,
-			Rid:         it.Rid,
-			Name:        it.Name,
-			Sale:        it.Sale,
-			Size:        it.Size,
-			TotalPrice:  it.TotalPrice,
-			NmId:        it.NmID,
-			Brand:       it.Brand,
-			Status:      it.Status,
-		})
-	}
-
-	return out, nil
-}
-
-func derefStr(p *string) string {
-	if p == nil {
-		return ""
-	}
-	return *p
-}
-
 type OrderResponse struct {
 	OrderUID        string           `json:"order_uid"`
 	TrackNumber     string           `json:"track_number"`
@@ -157,10 +12,47 @@ type OrderResponse struct {
 	Locale          string           `json:"locale"`
 	CustomerID      string           `json:"customer_id"`
 	DeliveryService string           `json:"delivery_service"`
+	Status          string           `json:"status"`
+	Version         int64            `json:"version"`
 	DateCreated     string           `json:"date_created"`
+	DeletedAt       string           `json:"deleted_at,omitempty"`
 	Delivery        DeliveryResponse `json:"delivery"`
 	Payment         PaymentResponse  `json:"payment"`
 	Items           []ItemResponse   `json:"items"`
+
+	Reporting *ReportingAmount `json:"reporting,omitempty"`
+}
+
+type SearchResponse struct {
+	Orders     []OrderResponse `json:"orders"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+
+	ReportingTotal *ReportingAmount `json:"reporting_total,omitempty"`
+}
+
+// ReportingAmount is a payment amount converted into the requested reporting
+// currency with the rate in effect at payment_dt.
+type ReportingAmount struct {
+	Currency      string `json:"currency"`
+	Amount        int64  `json:"amount"`
+	AmountDecimal string `json:"amount_decimal"`
+	Rate          string `json:"rate,omitempty"`
+	RateValidFrom string `json:"rate_valid_from,omitempty"`
+}
+
+func toReportingAmount(c orders.Conversion) *ReportingAmount {
+	out := &ReportingAmount{
+		Currency:      c.Amount.Currency.Code,
+		Amount:        c.Amount.Amount,
+		AmountDecimal: c.Amount.Decimal(),
+	}
+	if c.Rate.Value != nil {
+		out.Rate = c.Rate.Decimal()
+		if !c.Rate.ValidFrom.IsZero() {
+			out.RateValidFrom = c.Rate.ValidFrom.Format("2006-01-02T15:04:05Z07:00")
+		}
+	}
+	return out
 }
 
 type DeliveryResponse struct {
@@ -173,6 +65,9 @@ type DeliveryResponse struct {
 	Email   string `json:"email"`
 }
 
+// Amounts are in minor units of the payment currency; the *_decimal fields
+// carry the same amounts in major units ("10.50") and are omitted when the
+// stored currency is not a known ISO 4217 code.
 type PaymentResponse struct {
 	Transaction  string `json:"transaction"`
 	Currency     string `json:"currency"`
@@ -183,6 +78,11 @@ type PaymentResponse struct {
 	DeliveryCost int64  `json:"delivery_cost"`
 	GoodsTotal   int64  `json:"goods_total"`
 	CustomFee    int64  `json:"custom_fee"`
+
+	AmountDecimal       string `json:"amount_decimal,omitempty"`
+	DeliveryCostDecimal string `json:"delivery_cost_decimal,omitempty"`
+	GoodsTotalDecimal   string `json:"goods_total_decimal,omitempty"`
+	CustomFeeDecimal    string `json:"custom_fee_decimal,omitempty"`
 }
 
 type ItemResponse struct {
@@ -197,6 +97,20 @@ type ItemResponse struct {
 	NmID        string `json:"nm_id"`
 	Brand       string `json:"brand"`
 	Status      int64  `json:"status"`
+
+	PriceDecimal      string `json:"price_decimal,omitempty"`
+	SaleDecimal       string `json:"sale_decimal,omitempty"`
+	TotalPriceDecimal string `json:"total_price_decimal,omitempty"`
+}
+
+// decimal formats minor units in the payment currency, or returns "" when the
+// currency is unknown.
+func decimal(p order.Payment, minor int64) string {
+	m, err := p.Money(minor)
+	if err != nil {
+		return ""
+	}
+	return m.Decimal()
 }
 
 func ToResponse(o order.Order) OrderResponse {
@@ -207,6 +121,8 @@ func ToResponse(o order.Order) OrderResponse {
 		Locale:          o.Locale,
 		CustomerID:      o.CustomerId,
 		DeliveryService: o.DeliveryService,
+		Status:          string(o.Status),
+		Version:         o.Version,
 		DateCreated:     o.DateCreated.Format("2006-01-02T15:04:05Z07:00"),
 
 		Delivery: DeliveryResponse{
@@ -228,9 +144,17 @@ func ToResponse(o order.Order) OrderResponse {
 			DeliveryCost: o.Payment.DeliveryCost,
 			GoodsTotal:   o.Payment.GoodsTotal,
 			CustomFee:    o.Payment.CustomFee,
+
+			AmountDecimal:       decimal(o.Payment, o.Payment.Amount),
+			DeliveryCostDecimal: decimal(o.Payment, o.Payment.DeliveryCost),
+			GoodsTotalDecimal:   decimal(o.Payment, o.Payment.GoodsTotal),
+			CustomFeeDecimal:    decimal(o.Payment, o.Payment.CustomFee),
 		},
 		Items: make([]ItemResponse, 0, len(o.Items)),
 	}
+	if o.DeletedAt != nil {
+		resp.DeletedAt = o.DeletedAt.Format("2006-01-02T15:04:05Z07:00")
+	}
 
 	for _, it := range o.Items {
 		resp.Items = append(resp.Items, ItemResponse{
@@ -245,6 +169,10 @@ func ToResponse(o order.Order) OrderResponse {
 			NmID:        it.NmId,
 			Brand:       it.Brand,
 			Status:      it.Status,
+
+			PriceDecimal:      decimal(o.Payment, it.Price),
+			SaleDecimal:       decimal(o.Payment, it.Sale),
+			TotalPriceDecimal: decimal(o.Payment, it.TotalPrice),
 		})
 	}
 	return resp
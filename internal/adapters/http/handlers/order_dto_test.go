@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/reybrally/order-service/internal/domain/order"
+)
+
+func TestToResponseDecimalAmounts(t *testing.T) {
+	o := order.Order{
+		Payment: order.Payment{Currency: "KWD", Amount: 12345, DeliveryCost: 45, GoodsTotal: 12300},
+		Items:   []order.Item{{Price: 13300, Sale: 1000, TotalPrice: 12300}},
+	}
+
+	resp := ToResponse(o)
+	assert.Equal(t, "12.345", resp.Payment.AmountDecimal)
+	assert.Equal(t, "0.045", resp.Payment.DeliveryCostDecimal)
+	assert.Equal(t, "12.300", resp.Payment.GoodsTotalDecimal)
+	assert.Equal(t, "0.000", resp.Payment.CustomFeeDecimal)
+	assert.Equal(t, "13.300", resp.Items[0].PriceDecimal)
+	assert.Equal(t, "1.000", resp.Items[0].SaleDecimal)
+	assert.Equal(t, "12.300", resp.Items[0].TotalPriceDecimal)
+}
+
+func TestToResponseUnknownCurrencyOmitsDecimals(t *testing.T) {
+	o := order.Order{Payment: order.Payment{Currency: "XXY", Amount: 100}, Items: []order.Item{{Price: 100}}}
+
+	resp := ToResponse(o)
+	assert.Equal(t, int64(100), resp.Payment.Amount)
+	assert.Empty(t, resp.Payment.AmountDecimal)
+	assert.Empty(t, resp.Items[0].PriceDecimal)
+}
@@ -9,18 +9,32 @@ import (
 )
 
 type OrderHandlers struct {
-	svc serviceInterface
+	svc  serviceInterface
+	bulk BulkLimits
 }
 
 type serviceInterface interface {
 	CreateOrUpdateOrder(ctx context.Context, o order.Order) (order.Order, error)
+	ImportOrders(ctx context.Context, list []order.Order, opts orders.BulkOptions) ([]orders.BulkResult, error)
 	GetOrder(ctx context.Context, id string) (order.Order, error)
+	GetOrders(ctx context.Context, ids []string) ([]order.Order, []string, error)
+	TrackOrder(ctx context.Context, trackNumber string) (order.Order, error)
+	PatchOrder(ctx context.Context, id string, expectedVersion int64, apply func(order.Order) (order.Order, error)) (order.Order, error)
 	DeleteOrder(ctx context.Context, id string) error
-	SearchOrder(ctx context.Context, filters orders.SearchFilters, req orders.PageRequest) ([]order.Order, error)
+	SearchOrder(ctx context.Context, filters orders.SearchFilters, req orders.PageRequest) (orders.Page, error)
+	ExportOrders(ctx context.Context, filters orders.SearchFilters, sortBy, sortDir string, fn func(order.Order) error) error
+	TransitionOrder(ctx context.Context, id string, to order.Status, reason string) (order.StatusChange, error)
+	StatusHistory(ctx context.Context, id string) ([]order.StatusChange, error)
+	RestoreOrder(ctx context.Context, id string) (order.Order, error)
+	OrderHistory(ctx context.Context, id string) ([]order.Revision, error)
+	OrderRevision(ctx context.Context, id string, rev int64) (order.Revision, error)
+	ConsistencyReport(ctx context.Context, cursor string, limit int) (orders.ConsistencyReport, error)
+	ConvertPayments(ctx context.Context, list []order.Order, to string) ([]orders.Conversion, error)
+	OrderStats(ctx context.Context, f orders.SearchFilters, req orders.StatsRequest) (orders.Stats, error)
 }
 
-func NewOrderHandlers(svc serviceInterface) *OrderHandlers {
-	return &OrderHandlers{svc: svc}
+func NewOrderHandlers(svc serviceInterface, bulk BulkLimits) *OrderHandlers {
+	return &OrderHandlers{svc: svc, bulk: bulk}
 }
 
 func writeJSON(w http.ResponseWriter, code int, v any) {
@@ -29,14 +43,6 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-type errorResponse struct {
-	Error string `json:"error"`
-}
-
-func writeError(w http.ResponseWriter, code int, msg string) {
-	writeJSON(w, code, errorResponse{Error: msg})
-}
-
 func ToResponseList(src []order.Order) []OrderResponse {
 	out := make([]OrderResponse, 0, len(src))
 	for _, o := range src {
@@ -0,0 +1,80 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	cases := []struct {
+		name, doc, patch, want string
+	}{
+		{"replace scalar", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add member", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null removes", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"arrays are replaced", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"nested objects merge", `{"d":{"phone":"1","city":"x"}}`, `{"d":{"phone":"2"}}`, `{"d":{"city":"x","phone":"2"}}`},
+		{"nested null inside new object", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{"non-object patch replaces", `{"a":"b"}`, `["c"]`, `["c"]`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestJSONPatch(t *testing.T) {
+	cases := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add to array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{"append to array", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`},
+		{"remove element", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`},
+		{"replace nested", `{"d":{"phone":"1"}}`, `[{"op":"replace","path":"/d/phone","value":"2"}]`, `{"d":{"phone":"2"}}`},
+		{"move", `{"a":{"x":1},"b":{}}`, `[{"op":"move","from":"/a/x","path":"/b/y"}]`, `{"a":{},"b":{"y":1}}`},
+		{"copy", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"}]`, `{"a":[1],"b":[1]}`},
+		{"escaped pointer", `{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		{"test passes", `{"a":"x"}`, `[{"op":"test","path":"/a","value":"x"},{"op":"add","path":"/b","value":1}]`, `{"a":"x","b":1}`},
+		{"add null", `{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`},
+		{"replace with null", `{"a":1}`, `[{"op":"replace","path":"/a","value":null}]`, `{"a":null}`},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null},{"op":"remove","path":"/a"}]`, `{}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tc.doc), []byte(tc.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	cases := []struct {
+		name, patch string
+		want        error
+	}{
+		{"not an array", `{"op":"add"}`, ErrInvalidPatch},
+		{"unknown op", `[{"op":"frob","path":"/a"}]`, ErrInvalidPatch},
+		{"missing path", `[{"op":"replace","path":"/nope","value":1}]`, ErrInvalidPatch},
+		{"index out of range", `[{"op":"remove","path":"/arr/5"}]`, ErrInvalidPatch},
+		{"test fails", `[{"op":"test","path":"/a","value":2}]`, ErrTestFailed},
+		{"missing value", `[{"op":"add","path":"/b"}]`, ErrInvalidPatch},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := JSONPatch([]byte(`{"a":1,"arr":[1]}`), []byte(tc.patch))
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
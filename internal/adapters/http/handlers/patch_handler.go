@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/reybrally/order-service/internal/adapters/http/handlers/patch"
+	"github.com/reybrally/order-service/internal/adapters/orderdto"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
+	"github.com/reybrally/order-service/internal/logging"
+	"github.com/sirupsen/logrus"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// PatchHandler applies an RFC 7396 merge patch (the default) or an RFC 6902
+// JSON Patch to the stored order, as a patchDocument in the field names of
+// the API.
+func (h *OrderHandlers) PatchHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		logging.LogError("ID is required in PatchHandler", nil, logrus.Fields{"method": "PatchHandler"})
+		writeProblem(w, r, probBadRequest, "id can't be empty")
+		return
+	}
+
+	apply := patch.MergePatch
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		switch {
+		case err != nil:
+			writeProblem(w, r, probUnsupportedMedia, "invalid Content-Type")
+			return
+		case mt == jsonPatchType:
+			apply = patch.JSONPatch
+		case mt != mergePatchType && mt != "application/json":
+			writeProblem(w, r, probUnsupportedMedia, "Content-Type must be "+mergePatchType+" or "+jsonPatchType)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		logging.LogError("Error reading patch body", err, logrus.Fields{"method": "PatchHandler", "id": id})
+		writeBodyErr(w, r, err)
+		return
+	}
+
+	version, conditional, err := ifMatchVersion(r)
+	if err != nil {
+		logging.LogError("Invalid If-Match header", err, logrus.Fields{"method": "PatchHandler"})
+		writeIfMatchErr(w, r, err)
+		return
+	}
+
+	ord, err := h.svc.PatchOrder(r.Context(), id, version, func(cur order.Order) (order.Order, error) {
+		doc, err := json.Marshal(newPatchDocument(cur))
+		if err != nil {
+			return order.Order{}, err
+		}
+		patched, err := apply(doc, body)
+		if err != nil {
+			return order.Order{}, err
+		}
+		var pd patchDocument
+		dec := json.NewDecoder(bytes.NewReader(patched))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&pd); err != nil {
+			return order.Order{}, fmt.Errorf("%w: %v", patch.ErrInvalidPatch, err)
+		}
+		next, err := pd.order(cur)
+		if err != nil {
+			return order.Order{}, err
+		}
+		if err := validation.IsValidPatchedOrder(cur, next); err != nil {
+			return order.Order{}, err
+		}
+		return next, nil
+	})
+	if err != nil {
+		logging.LogError("Error patching order", err, logrus.Fields{"method": "PatchHandler", "id": id})
+		// If-Match: * fails on a missing order like any other tag.
+		if conditional && (errors.Is(err, orders.ErrConflict) || errors.Is(err, orders.ErrNotFound)) {
+			writeProblem(w, r, probPreconditionFailed, "order version does not match If-Match")
+			return
+		}
+		writeErr(w, r, err)
+		return
+	}
+
+	logging.LogInfo("Order patched", logrus.Fields{"method": "PatchHandler", "order_uid": ord.OrderUID})
+	w.Header().Set("ETag", etag(ord.Version))
+	writeJSON(w, http.StatusOK, ToResponse(ord))
+}
+
+// patchDocument is the part of an order a PATCH can change. order_uid, status
+// and version are not patchable, and the internal fields (internal_signature,
+// shard_key, sm_id, oof_shard) are not in it, so a patch that touches them is
+// rejected.
+type patchDocument struct {
+	TrackNumber     string               `json:"track_number"`
+	Entry           string               `json:"entry"`
+	Locale          string               `json:"locale"`
+	CustomerID      string               `json:"customer_id"`
+	DeliveryService string               `json:"delivery_service"`
+	DateCreated     string               `json:"date_created"`
+	Delivery        orderdto.DeliveryDTO `json:"delivery"`
+	Payment         orderdto.PaymentDTO  `json:"payment"`
+	Items           []orderdto.ItemDTO   `json:"items"`
+}
+
+func newPatchDocument(o order.Order) patchDocument {
+	req := orderdto.FromModel(o)
+	return patchDocument{
+		TrackNumber:     req.TrackNumber,
+		Entry:           req.Entry,
+		Locale:          req.Locale,
+		CustomerID:      req.CustomerID,
+		DeliveryService: req.DeliveryService,
+		DateCreated:     req.DateCreatedRFC,
+		Delivery:        req.Delivery,
+		Payment:         req.Payment,
+		Items:           req.Items,
+	}
+}
+
+// order returns cur with the fields of d, through the same conversion as a PUT.
+func (d patchDocument) order(cur order.Order) (order.Order, error) {
+	if d.DateCreated == "" {
+		// ToModel would take it for a new order created now.
+		return order.Order{}, fmt.Errorf("%w: date_created cannot be removed", patch.ErrInvalidPatch)
+	}
+	req := orderdto.FromModel(cur)
+	req.TrackNumber = d.TrackNumber
+	req.Entry = d.Entry
+	req.Locale = d.Locale
+	req.CustomerID = d.CustomerID
+	req.DeliveryService = d.DeliveryService
+	req.DateCreatedRFC = d.DateCreated
+	req.Delivery = d.Delivery
+	req.Payment = d.Payment
+	req.Items = d.Items
+
+	next, err := req.ToModel()
+	if err != nil {
+		return order.Order{}, fmt.Errorf("%w: %v", patch.ErrInvalidPatch, err)
+	}
+	next.InternalSignature = cur.InternalSignature
+	return next, nil
+}
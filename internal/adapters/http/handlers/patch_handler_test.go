@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// patchSvc applies patches to a single stored order, which is missing when
+// cur has no order_uid; any other method panics.
+type patchSvc struct {
+	serviceInterface
+	cur order.Order
+}
+
+func (s *patchSvc) PatchOrder(_ context.Context, _ string, version int64, apply func(order.Order) (order.Order, error)) (order.Order, error) {
+	if s.cur.OrderUID == "" {
+		return order.Order{}, orders.ErrNotFound
+	}
+	if version > 0 && version != s.cur.Version {
+		return order.Order{}, orders.ErrConflict
+	}
+	next, err := apply(s.cur)
+	if err != nil {
+		return order.Order{}, err
+	}
+	next.Version = s.cur.Version + 1
+	s.cur = next
+	return next, nil
+}
+
+func patchableOrder(created time.Time) order.Order {
+	return order.Order{
+		OrderUID: "b563feb7b2b84b6test", TrackNumber: "WBILMTESTTRACK", Entry: "WBIL", Locale: "en",
+		CustomerId: "test", DeliveryService: "meest", DateCreated: created, Version: 3,
+		Delivery: order.Delivery{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+		Payment: order.Payment{Transaction: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay", Amount: 1817,
+			PaymentDt: created, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []order.Item{{ChrtId: "9934930", TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "rid", Name: "Mascaras",
+			Sale: 30, TotalPrice: 317, NmId: "2389212", Brand: "Vivienne Sabo"}},
+	}
+}
+
+func patchOrder(t *testing.T, svc serviceInterface, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	return patchOrderWith(t, svc, nil, body)
+}
+
+// patchOrderWith sends a merge patch unless headers set another Content-Type.
+func patchOrderWith(t *testing.T, svc serviceInterface, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	logging.InitLogger()
+	r := chi.NewRouter()
+	r.Patch("/orders/{id}", NewOrderHandlers(svc, BulkLimits{}).PatchHandler)
+	req := httptest.NewRequest(http.MethodPatch, "/orders/b563feb7b2b84b6test", strings.NewReader(body))
+	req.Header.Set("Content-Type", mergePatchType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPatchOrderOlderThanAYear(t *testing.T) {
+	svc := &patchSvc{cur: patchableOrder(time.Now().AddDate(-2, 0, 0))}
+
+	w := patchOrder(t, svc, `{"delivery": {"city": "Haifa"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp OrderResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "Haifa", resp.Delivery.City)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
+
+func TestPatchOrderRejectsStaleDateCreated(t *testing.T) {
+	svc := &patchSvc{cur: patchableOrder(time.Now().Add(-time.Hour))}
+	stale := time.Now().AddDate(-2, 0, 0).UTC().Format(time.RFC3339)
+
+	w := patchOrder(t, svc, `{"date_created": "`+stale+`"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"path":"date_created"`)
+}
+
+func TestPatchOrderStillValidates(t *testing.T) {
+	svc := &patchSvc{cur: patchableOrder(time.Now().AddDate(-2, 0, 0))}
+
+	w := patchOrder(t, svc, `{"delivery": {"city": ""}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"path":"delivery.city"`)
+}
+
+func TestPatchOrderIfMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		missing bool
+		ifMatch string
+		want    int
+	}{
+		{name: "current version", ifMatch: `"3"`, want: http.StatusOK},
+		{name: "stale version", ifMatch: `"2"`, want: http.StatusPreconditionFailed},
+		{name: "weak tag", ifMatch: `W/"3"`, want: http.StatusPreconditionFailed},
+		{name: "malformed", ifMatch: `3`, want: http.StatusBadRequest},
+		{name: "wildcard", ifMatch: `*`, want: http.StatusOK},
+		{name: "wildcard on a missing order", missing: true, ifMatch: `*`, want: http.StatusPreconditionFailed},
+		{name: "unconditional on a missing order", missing: true, want: http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &patchSvc{cur: patchableOrder(time.Now().Add(-time.Hour))}
+			if tc.missing {
+				svc.cur = order.Order{}
+			}
+			var headers map[string]string
+			if tc.ifMatch != "" {
+				headers = map[string]string{"If-Match": tc.ifMatch}
+			}
+			w := patchOrderWith(t, svc, headers, `{"delivery": {"city": "Haifa"}}`)
+			assert.Equal(t, tc.want, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestPatchOrderItemsByAPIFieldNames(t *testing.T) {
+	jsonPatch := map[string]string{"Content-Type": jsonPatchType}
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		body    string
+	}{
+		{name: "merge patch", body: `{"items": [{"chrt_id": "9934930", "track_number": "WBILMTESTTRACK", "price": 453,
+			"rid": "rid", "item_name": "Eyeliner", "sale": 30, "item_size": 2, "total_price": 317, "nm_id": "2389212",
+			"brand": "Vivienne Sabo"}]}`},
+		{name: "json patch", headers: jsonPatch, body: `[
+			{"op": "test", "path": "/items/0/item_name", "value": "Mascaras"},
+			{"op": "replace", "path": "/items/0/item_name", "value": "Eyeliner"},
+			{"op": "replace", "path": "/items/0/item_size", "value": 2}]`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cur := patchableOrder(time.Now().Add(-time.Hour))
+			cur.InternalSignature, cur.ShardKey, cur.SmId, cur.OofShard = "sig", "9", 99, 1
+			svc := &patchSvc{cur: cur}
+
+			w := patchOrderWith(t, svc, tc.headers, tc.body)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			require.Len(t, svc.cur.Items, 1)
+			assert.Equal(t, "Eyeliner", svc.cur.Items[0].Name)
+			assert.Equal(t, int64(2), svc.cur.Items[0].Size)
+			assert.Equal(t, "Vivienne Sabo", svc.cur.Items[0].Brand)
+			assert.Equal(t, "sig", svc.cur.InternalSignature)
+			assert.Equal(t, "9", svc.cur.ShardKey)
+			assert.Equal(t, int64(99), svc.cur.SmId)
+			assert.Equal(t, int64(1), svc.cur.OofShard)
+			assert.True(t, svc.cur.DateCreated.Equal(cur.DateCreated))
+		})
+	}
+}
+
+func TestPatchOrderRejectsNonAPIFields(t *testing.T) {
+	jsonPatch := map[string]string{"Content-Type": jsonPatchType}
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		body    string
+	}{
+		{name: "internal field", body: `{"internal_signature": "forged"}`},
+		{name: "routing field", body: `{"shard_key": "1", "sm_id": 1}`},
+		{name: "domain item name", body: `{"items": [{"name": "Eyeliner"}]}`},
+		{name: "domain item path", headers: jsonPatch, body: `[{"op": "replace", "path": "/items/0/name", "value": "Eyeliner"}]`},
+		{name: "removed date_created", body: `{"date_created": null}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cur := patchableOrder(time.Now().Add(-time.Hour))
+			svc := &patchSvc{cur: cur}
+
+			w := patchOrderWith(t, svc, tc.headers, tc.body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			assert.Equal(t, cur, svc.cur)
+		})
+	}
+}
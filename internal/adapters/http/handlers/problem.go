@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/reybrally/order-service/internal/adapters/http/handlers/patch"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	problemTypePrefix  = "/problems/"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type      string         `json:"type"`
+	Title     string         `json:"title"`
+	Status    int            `json:"status"`
+	Detail    string         `json:"detail,omitempty"`
+	Instance  string         `json:"instance,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Errors    []FieldProblem `json:"errors,omitempty"`
+}
+
+type FieldProblem struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type problemKind struct {
+	status int
+	slug   string
+	title  string
+}
+
+var (
+	probBadRequest         = problemKind{http.StatusBadRequest, "bad-request", "Bad request"}
+	probMalformedBody      = problemKind{http.StatusBadRequest, "malformed-body", "Malformed request body"}
+	probInvalidCursor      = problemKind{http.StatusBadRequest, "invalid-cursor", "Invalid cursor"}
+	probInvalidPatch       = problemKind{http.StatusBadRequest, "invalid-patch", "Invalid patch document"}
+	probUnknownStatus      = problemKind{http.StatusBadRequest, "unknown-status", "Unknown order status"}
+	probUnknownCurrency    = problemKind{http.StatusBadRequest, "unknown-currency", "Unknown currency"}
+	probNotFound           = problemKind{http.StatusNotFound, "not-found", "Resource not found"}
+	probConflict           = problemKind{http.StatusConflict, "conflict", "Conflict"}
+	probAlreadyExists      = problemKind{http.StatusConflict, "already-exists", "Resource already exists"}
+	probInvalidTransition  = problemKind{http.StatusConflict, "invalid-transition", "Status transition not allowed"}
+	probPatchTestFailed    = problemKind{http.StatusConflict, "patch-test-failed", "Patch test operation failed"}
+	probIdempotencyBusy    = problemKind{http.StatusConflict, "idempotency-key-in-progress", "A request with this idempotency key is in progress"}
+	probPreconditionFailed = problemKind{http.StatusPreconditionFailed, "precondition-failed", "Precondition failed"}
+	probBodyTooLarge       = problemKind{http.StatusRequestEntityTooLarge, "body-too-large", "Request body too large"}
+	probUnsupportedMedia   = problemKind{http.StatusUnsupportedMediaType, "unsupported-media-type", "Unsupported media type"}
+	probValidation         = problemKind{http.StatusUnprocessableEntity, "validation-failed", "Validation failed"}
+	probInconsistent       = problemKind{http.StatusUnprocessableEntity, "inconsistent-totals", "Order totals are inconsistent"}
+	probInvalidData        = problemKind{http.StatusUnprocessableEntity, "invalid-data", "Invalid data"}
+	probInvalidReference   = problemKind{http.StatusUnprocessableEntity, "invalid-reference", "Invalid reference"}
+	probNoExchangeRate     = problemKind{http.StatusUnprocessableEntity, "no-exchange-rate", "No exchange rate"}
+	probIdempotencyReused  = problemKind{http.StatusUnprocessableEntity, "idempotency-key-reused", "Idempotency key reused with a different request"}
+	probBulkAborted        = problemKind{http.StatusFailedDependency, "bulk-aborted", "Not stored, another record of the import failed"}
+	probRetryable          = problemKind{http.StatusServiceUnavailable, "retryable", "Temporary failure, retry the request"}
+	probTimeout            = problemKind{http.StatusGatewayTimeout, "timeout", "Timed out"}
+	probInternal           = problemKind{http.StatusInternalServerError, "internal", "Internal server error"}
+)
+
+// errorKinds maps sentinel errors to problems; the first match wins.
+var errorKinds = []struct {
+	err  error
+	kind problemKind
+}{
+	{orders.ErrNotFound, probNotFound},
+	{orders.ErrAlreadyExists, probAlreadyExists},
+	{orders.ErrConflict, probConflict},
+	{orders.ErrInvalidData, probInvalidData},
+	{orders.ErrInvalidReference, probInvalidReference},
+	{orders.ErrInvalidCursor, probInvalidCursor},
+	{orders.ErrNoExchangeRate, probNoExchangeRate},
+	{orders.ErrBulkAborted, probBulkAborted},
+	{money.ErrUnknownCurrency, probUnknownCurrency},
+	{orders.ErrRetryable, probRetryable},
+	{orders.ErrRetry, probRetryable},
+	{orders.ErrTimeout, probTimeout},
+	{order.ErrInvalidTransition, probInvalidTransition},
+	{order.ErrUnknownStatus, probUnknownStatus},
+	{patch.ErrTestFailed, probPatchTestFailed},
+	{patch.ErrInvalidPatch, probInvalidPatch},
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, kind problemKind, detail string, fields ...FieldProblem) {
+	p := Problem{
+		Type:      problemTypePrefix + kind.slug,
+		Title:     kind.title,
+		Status:    kind.status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: middleware.GetReqID(r.Context()),
+		Errors:    fields,
+	}
+	if kind == probRetryable || kind == probIdempotencyBusy {
+		w.Header().Set("Retry-After", "1")
+	}
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(kind.status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// writeErr turns err into a problem response. Unknown errors become a 500
+// without leaking their text to the client.
+func writeErr(w http.ResponseWriter, r *http.Request, err error) {
+	kind, detail, fields := classifyErr(err)
+	writeProblem(w, r, kind, detail, fields...)
+}
+
+// classifyErr picks the problem kind, detail and field problems reported for
+// err.
+func classifyErr(err error) (problemKind, string, []FieldProblem) {
+	var (
+		violations   validation.Violations
+		inconsistent order.InconsistencyError
+		tooLarge     *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &violations):
+		fields := make([]FieldProblem, 0, len(violations))
+		for _, v := range violations {
+			fields = append(fields, FieldProblem{Path: v.Path, Code: v.Code, Message: v.Message})
+		}
+		return probValidation, "order failed validation", fields
+	case errors.As(err, &inconsistent):
+		fields := make([]FieldProblem, 0, len(inconsistent))
+		for _, in := range inconsistent {
+			fields = append(fields, FieldProblem{Path: in.Path, Code: in.Code, Message: in.Message})
+		}
+		return probInconsistent, "payment and item totals do not add up", fields
+	case errors.As(err, &tooLarge):
+		return probBodyTooLarge, err.Error(), nil
+	}
+	for _, ek := range errorKinds {
+		if errors.Is(err, ek.err) {
+			return ek.kind, err.Error(), nil
+		}
+	}
+	return probInternal, "", nil
+}
+
+// writeBodyErr reports a request body that could not be decoded.
+func writeBodyErr(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeProblem(w, r, probBodyTooLarge, err.Error())
+		return
+	}
+	writeProblem(w, r, probMalformedBody, err.Error())
+}
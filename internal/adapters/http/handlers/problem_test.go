@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
+)
+
+func TestWriteErrMapsSentinels(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		typ    string
+	}{
+		{orders.ErrNotFound, http.StatusNotFound, "/problems/not-found"},
+		{fmt.Errorf("wrapped: %w", orders.ErrConflict), http.StatusConflict, "/problems/conflict"},
+		{orders.ErrInvalidData, http.StatusUnprocessableEntity, "/problems/invalid-data"},
+		{orders.ErrInvalidReference, http.StatusUnprocessableEntity, "/problems/invalid-reference"},
+		{orders.ErrRetryable, http.StatusServiceUnavailable, "/problems/retryable"},
+		{orders.ErrTimeout, http.StatusGatewayTimeout, "/problems/timeout"},
+		{order.ErrInvalidTransition, http.StatusConflict, "/problems/invalid-transition"},
+		{errors.New("boom"), http.StatusInternalServerError, "/problems/internal"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.typ, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeErr(w, httptest.NewRequest(http.MethodGet, "/orders/x", nil), tc.err)
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var p Problem
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+			assert.Equal(t, tc.typ, p.Type)
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, "/orders/x", p.Instance)
+		})
+	}
+}
+
+func TestWriteErrInternalHidesDetail(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeErr(w, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("pq: password=secret"))
+
+	assert.NotContains(t, w.Body.String(), "secret")
+}
+
+func TestWriteErrValidationFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	var w *httptest.ResponseRecorder
+	middleware.RequestID(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		w = httptest.NewRecorder()
+		writeErr(w, r, validation.Violations{
+			{Path: "items[1].brand", Code: validation.CodeRequired, Message: "brand must not be empty"},
+			{Path: "payment.amount", Code: validation.CodeNegative, Message: "amount is negative"},
+		})
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var p Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, "/problems/validation-failed", p.Type)
+	assert.NotEmpty(t, p.RequestID)
+	require.Len(t, p.Errors, 2)
+	assert.Equal(t, FieldProblem{Path: "items[1].brand", Code: "required", Message: "brand must not be empty"}, p.Errors[0])
+	assert.Equal(t, "payment.amount", p.Errors[1].Path)
+}
+
+func TestWriteErrInconsistentTotals(t *testing.T) {
+	err := fmt.Errorf("%w: %w", orders.ErrInvalidData, order.InconsistencyError{
+		{Path: "payment.amount", Code: order.CodeAmountMismatch, Message: "amount 10 must equal 12", Expected: 12, Actual: 10},
+	})
+	w := httptest.NewRecorder()
+	writeErr(w, httptest.NewRequest(http.MethodPost, "/orders", nil), err)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var p Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&p))
+	assert.Equal(t, "/problems/inconsistent-totals", p.Type)
+	require.Len(t, p.Errors, 1)
+	assert.Equal(t, FieldProblem{Path: "payment.amount", Code: "amount_mismatch", Message: "amount 10 must equal 12"}, p.Errors[0])
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+func (h *OrderHandlers) RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		logging.LogError("ID is required in RestoreHandler", nil, logrus.Fields{"method": "RestoreHandler"})
+		writeProblem(w, r, probBadRequest, "id can't be empty")
+		return
+	}
+
+	logging.LogInfo("Attempting to restore order", logrus.Fields{"method": "RestoreHandler", "id": id})
+	out, err := h.svc.RestoreOrder(r.Context(), id)
+	if err != nil {
+		logging.LogError("Error restoring order", err, logrus.Fields{"method": "RestoreHandler", "id": id})
+		if errors.Is(err, orders.ErrConflict) {
+			writeProblem(w, r, probConflict, "order is not deleted")
+			return
+		}
+		writeErr(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(out.Version))
+	writeJSON(w, http.StatusOK, ToResponse(out))
+}
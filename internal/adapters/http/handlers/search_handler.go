@@ -1,39 +1,115 @@
 package handlers
 
 import (
+	"fmt"
 	"github.com/reybrally/order-service/internal/adapters/http/handlers/normalization"
 	"github.com/reybrally/order-service/internal/logging"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
 )
 
 func (h *OrderHandlers) SearchOrders(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
 
-	var (
-		f orders.SearchFilters
-		p orders.PageRequest
-	)
+	f, ok := parseSearchFilters(w, r, "SearchOrders")
+	if !ok {
+		return
+	}
+
+	var p orders.PageRequest
+	if s := q.Get("limit"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			logging.LogError("Invalid 'limit' query parameter", err, logrus.Fields{"method": "SearchOrders"})
+			writeProblem(w, r, probBadRequest, "invalid limit (non-negative integer expected)")
+			return
+		}
+		p.Limit = v
+	}
+	if s := q.Get("offset"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			logging.LogError("Invalid 'offset' query parameter", err, logrus.Fields{"method": "SearchOrders"})
+			writeProblem(w, r, probBadRequest, "invalid offset (non-negative integer expected)")
+			return
+		}
+		p.Offset = v
+	}
+	p.SortBy = q.Get("sort_by")
+	p.SortDir = q.Get("sort_dir")
+	p.Cursor = q.Get("cursor")
+	if !checkSort(w, r, "SearchOrders", f, p.SortBy) {
+		return
+	}
+	reporting := strings.ToUpper(strings.TrimSpace(q.Get("reporting_currency")))
+
+	normalization.NormalizeRequest(&p)
+
+	logging.LogDebug("Search filters", logrus.Fields{"method": "SearchOrders", "filters": f})
+	logging.LogDebug("Page request", logrus.Fields{"method": "SearchOrders", "page_request": p})
+
+	ctx := r.Context()
+	page, err := h.svc.SearchOrder(ctx, f, p)
+	if err != nil {
+		logging.LogError("Error searching orders", err, logrus.Fields{"method": "SearchOrders"})
+		writeErr(w, r, err)
+		return
+	}
+
+	logging.LogInfo("Orders found", logrus.Fields{"method": "SearchOrders", "count": len(page.Orders)})
+
+	resp := SearchResponse{
+		Orders:     ToResponseList(page.Orders),
+		NextCursor: page.NextCursor,
+	}
+	if reporting != "" {
+		conv, err := h.svc.ConvertPayments(ctx, page.Orders, reporting)
+		if err != nil {
+			logging.LogError("Error converting search results", err, logrus.Fields{"method": "SearchOrders", "reporting_currency": reporting})
+			writeErr(w, r, err)
+			return
+		}
+		var total orders.Conversion
+		total.Amount.Currency, _ = money.LookupCurrency(reporting) // already validated by ConvertPayments
+		for i, c := range conv {
+			resp.Orders[i].Reporting = toReportingAmount(c)
+			total.Amount.Amount += c.Amount.Amount
+		}
+		resp.ReportingTotal = toReportingAmount(total)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseSearchFilters reads the filters shared by search and stats from the
+// query string. On a bad value it writes the problem and returns false.
+func parseSearchFilters(w http.ResponseWriter, r *http.Request, method string) (orders.SearchFilters, bool) {
+	q := r.URL.Query()
+	var f orders.SearchFilters
 
 	if s := q.Get("created_from"); s != "" {
 		if t, err := time.Parse(time.RFC3339, s); err == nil {
 			f.CreatedFrom = &t
 		} else {
-			logging.LogError("Invalid 'created_from' query parameter", err, logrus.Fields{"method": "SearchOrders"})
-			writeError(w, http.StatusBadRequest, "invalid created_from (RFC3339 expected)")
-			return
+			logging.LogError("Invalid 'created_from' query parameter", err, logrus.Fields{"method": method})
+			writeProblem(w, r, probBadRequest, "invalid created_from (RFC3339 expected)")
+			return f, false
 		}
 	}
 	if s := q.Get("created_to"); s != "" {
 		if t, err := time.Parse(time.RFC3339, s); err == nil {
 			f.CreatedTo = &t
 		} else {
-			logging.LogError("Invalid 'created_to' query parameter", err, logrus.Fields{"method": "SearchOrders"})
-			writeError(w, http.StatusBadRequest, "invalid created_to (RFC3339 expected)")
-			return
+			logging.LogError("Invalid 'created_to' query parameter", err, logrus.Fields{"method": method})
+			writeProblem(w, r, probBadRequest, "invalid created_to (RFC3339 expected)")
+			return f, false
 		}
 	}
 
@@ -44,22 +120,81 @@ func (h *OrderHandlers) SearchOrders(w http.ResponseWriter, r *http.Request) {
 	f.Currency = strptr(q.Get("currency"))
 	f.Query = strptr(q.Get("q"))
 
-	normalization.NormalizeSearchFilters(&f)
+	lists := []struct {
+		key string
+		dst *[]string
+	}{
+		{"nm_id", &f.NmIDs},
+		{"brand", &f.Brands},
+		{"chrt_id", &f.ChrtIDs},
+		{"rid", &f.Rids},
+		{"delivery_service", &f.DeliveryServices},
+		{"city", &f.Cities},
+		{"region", &f.Regions},
+		{"phone", &f.Phones},
+		{"email", &f.Emails},
+	}
+	for _, l := range lists {
+		*l.dst = queryList(q, l.key)
+		if len(*l.dst) > maxFilterValues {
+			logging.LogError("Too many values for a search filter", nil, logrus.Fields{"method": method, "filter": l.key, "count": len(*l.dst)})
+			writeProblem(w, r, probBadRequest, fmt.Sprintf("too many values for %s (at most %d)", l.key, maxFilterValues))
+			return f, false
+		}
+	}
 
-	normalization.NormalizeRequest(&p)
+	for _, a := range []struct {
+		key string
+		dst **int64
+	}{{"amount_min", &f.AmountMin}, {"amount_max", &f.AmountMax}} {
+		s := q.Get(a.key)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 {
+			logging.LogError("Invalid '"+a.key+"' query parameter", err, logrus.Fields{"method": method})
+			writeProblem(w, r, probBadRequest, "invalid "+a.key+" (non-negative integer in minor units expected)")
+			return f, false
+		}
+		*a.dst = &v
+	}
 
-	logging.LogDebug("Search filters", logrus.Fields{"method": "SearchOrders", "filters": f})
-	logging.LogDebug("Page request", logrus.Fields{"method": "SearchOrders", "page_request": p})
+	if s := q.Get("include_deleted"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			logging.LogError("Invalid 'include_deleted' query parameter", err, logrus.Fields{"method": method})
+			writeProblem(w, r, probBadRequest, "invalid include_deleted (bool expected)")
+			return f, false
+		}
+		f.IncludeDeleted = v
+	}
 
-	ctx := r.Context()
-	list, err := h.svc.SearchOrder(ctx, f, p)
-	if err != nil {
-		logging.LogError("Error searching orders", err, logrus.Fields{"method": "SearchOrders"})
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+	normalization.NormalizeSearchFilters(&f)
+	return f, true
+}
+
+// checkSort rejects sort_by=relevance without q, which has nothing to rank
+// against. On a bad value it writes the problem and returns false.
+func checkSort(w http.ResponseWriter, r *http.Request, method string, f orders.SearchFilters, sortBy string) bool {
+	if sortBy == "relevance" && f.Query == nil {
+		logging.LogError("sort_by=relevance without q", nil, logrus.Fields{"method": method})
+		writeProblem(w, r, probBadRequest, "sort_by=relevance requires q")
+		return false
 	}
+	return true
+}
 
-	logging.LogInfo("Orders found", logrus.Fields{"method": "SearchOrders", "count": len(list)})
+// maxFilterValues caps each multi-value filter so that a query string cannot
+// build an arbitrarily large IN list.
+const maxFilterValues = 100
 
-	writeJSON(w, http.StatusOK, ToResponseList(list))
+// queryList collects the values of a multi-value filter given either as
+// repeated parameters (brand=a&brand=b) or comma-separated (brand=a,b).
+func queryList(q url.Values, key string) []string {
+	var out []string
+	for _, v := range q[key] {
+		out = append(out, strings.Split(v, ",")...)
+	}
+	return out
 }
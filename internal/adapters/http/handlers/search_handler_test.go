@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+func TestParseSearchFiltersLists(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet,
+		"/orders/search?brand=Vivienne+Sabo,%20Nike&brand=nike&nm_id=1&nm_id=2,&city=Moscow&email=John@Example.com&delivery_service=meest&amount_min=500&amount_max=100", nil)
+	w := httptest.NewRecorder()
+
+	f, ok := parseSearchFilters(w, r, "test")
+
+	require.True(t, ok, w.Body.String())
+	assert.Equal(t, []string{"vivienne sabo", "nike"}, f.Brands)
+	assert.Equal(t, []string{"1", "2"}, f.NmIDs)
+	assert.Equal(t, []string{"moscow"}, f.Cities)
+	assert.Equal(t, []string{"john@example.com"}, f.Emails)
+	assert.Equal(t, []string{"meest"}, f.DeliveryServices)
+	assert.Nil(t, f.Rids)
+	require.NotNil(t, f.AmountMin)
+	require.NotNil(t, f.AmountMax)
+	assert.Equal(t, int64(100), *f.AmountMin)
+	assert.Equal(t, int64(500), *f.AmountMax)
+}
+
+func TestParseSearchFiltersRejectsBadValues(t *testing.T) {
+	logging.InitLogger()
+	for name, query := range map[string]string{
+		"negative amount":   "amount_min=-1",
+		"fractional amount": "amount_max=10.5",
+		"too many values":   "rid=" + strings.Repeat("x,", maxFilterValues) + "x",
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, ok := parseSearchFilters(w, httptest.NewRequest(http.MethodGet, "/orders/search?"+query, nil), "test")
+
+			assert.False(t, ok)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestSearchOrdersRejectsRelevanceWithoutQuery(t *testing.T) {
+	logging.InitLogger()
+	// The service is never reached: a nil serviceInterface would panic.
+	h := NewOrderHandlers(struct{ serviceInterface }{}, BulkLimits{})
+	for _, query := range []string{"sort_by=relevance", "sort_by=relevance&q=%20"} {
+		w := httptest.NewRecorder()
+		h.SearchOrders(w, httptest.NewRequest(http.MethodGet, "/orders/search?"+query, nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), "requires q", query)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+type StatsGroupResponse struct {
+	Key                  string `json:"key"`
+	Currency             string `json:"currency"`
+	Orders               int64  `json:"orders"`
+	Items                int64  `json:"items"`
+	Revenue              int64  `json:"revenue"`
+	RevenueDecimal       string `json:"revenue_decimal"`
+	AvgOrderValue        int64  `json:"avg_order_value"`
+	AvgOrderValueDecimal string `json:"avg_order_value_decimal"`
+}
+
+type StatsResponse struct {
+	GroupBy           string               `json:"group_by"`
+	ReportingCurrency string               `json:"reporting_currency,omitempty"`
+	Groups            []StatsGroupResponse `json:"groups"`
+}
+
+// StatsHandler aggregates the orders matching the search filters by the
+// group_by dimension.
+func (h *OrderHandlers) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	f, ok := parseSearchFilters(w, r, "StatsHandler")
+	if !ok {
+		return
+	}
+
+	req := orders.StatsRequest{
+		GroupBy:           strings.ToLower(strings.TrimSpace(q.Get("group_by"))),
+		ReportingCurrency: strings.ToUpper(strings.TrimSpace(q.Get("reporting_currency"))),
+	}
+	if req.GroupBy == "" {
+		req.GroupBy = orders.StatsByDay
+	}
+	if !orders.ValidStatsDimension(req.GroupBy) {
+		writeProblem(w, r, probBadRequest, "invalid group_by (one of "+strings.Join(orders.StatsDimensions, ", ")+" expected)")
+		return
+	}
+
+	stats, err := h.svc.OrderStats(r.Context(), f, req)
+	if err != nil {
+		logging.LogError("Error computing order stats", err, logrus.Fields{"method": "StatsHandler", "group_by": req.GroupBy})
+		writeErr(w, r, err)
+		return
+	}
+
+	out := StatsResponse{
+		GroupBy:           stats.GroupBy,
+		ReportingCurrency: req.ReportingCurrency,
+		Groups:            make([]StatsGroupResponse, 0, len(stats.Groups)),
+	}
+	for _, g := range stats.Groups {
+		out.Groups = append(out.Groups, StatsGroupResponse{
+			Key:                  g.Key,
+			Currency:             g.Revenue.Currency.Code,
+			Orders:               g.Orders,
+			Items:                g.Items,
+			Revenue:              g.Revenue.Amount,
+			RevenueDecimal:       g.Revenue.Decimal(),
+			AvgOrderValue:        g.AvgOrderValue.Amount,
+			AvgOrderValueDecimal: g.AvgOrderValue.Decimal(),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// TrackingResponse is the public view of an order found by its track number.
+// It leaves out the order_uid, customer and payment, and masks the recipient.
+type TrackingResponse struct {
+	TrackNumber     string           `json:"track_number"`
+	Status          string           `json:"status"`
+	DeliveryService string           `json:"delivery_service"`
+	DateCreated     string           `json:"date_created"`
+	Delivery        TrackingDelivery `json:"delivery"`
+	ItemsCount      int              `json:"items_count"`
+	Items           []TrackingItem   `json:"items"`
+}
+
+type TrackingDelivery struct {
+	Name    string `json:"name"`
+	Phone   string `json:"phone"`
+	City    string `json:"city"`
+	Region  string `json:"region"`
+	Address string `json:"address"`
+}
+
+type TrackingItem struct {
+	Name  string `json:"name"`
+	Brand string `json:"brand"`
+}
+
+// TrackingHandler serves GET /tracking/{track_number}.
+func (h *OrderHandlers) TrackingHandler(w http.ResponseWriter, r *http.Request) {
+	track := strings.TrimSpace(chi.URLParam(r, "track_number"))
+	if track == "" {
+		logging.LogError("Track number is required", nil, logrus.Fields{"method": "TrackingHandler"})
+		writeProblem(w, r, probBadRequest, "track_number is required")
+		return
+	}
+
+	o, err := h.svc.TrackOrder(r.Context(), track)
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFound) {
+			logging.LogError("Order not found by track number", err, logrus.Fields{"method": "TrackingHandler", "track_number": track})
+			writeProblem(w, r, probNotFound, "order not found")
+			return
+		}
+		logging.LogError("Error fetching order by track number", err, logrus.Fields{"method": "TrackingHandler", "track_number": track})
+		writeErr(w, r, err)
+		return
+	}
+	logging.LogInfo("Order tracked", logrus.Fields{"method": "TrackingHandler", "track_number": track})
+	writeJSON(w, http.StatusOK, ToTrackingResponse(o))
+}
+
+func ToTrackingResponse(o order.Order) TrackingResponse {
+	out := TrackingResponse{
+		TrackNumber:     o.TrackNumber,
+		Status:          string(o.Status),
+		DeliveryService: o.DeliveryService,
+		DateCreated:     o.DateCreated.Format("2006-01-02T15:04:05Z07:00"),
+		Delivery: TrackingDelivery{
+			Name:    maskWords(o.Delivery.Name),
+			Phone:   maskPhone(o.Delivery.Phone),
+			City:    o.Delivery.City,
+			Region:  o.Delivery.Region,
+			Address: maskWords(o.Delivery.Address),
+		},
+		ItemsCount: len(o.Items),
+		Items:      make([]TrackingItem, 0, len(o.Items)),
+	}
+	for _, it := range o.Items {
+		out.Items = append(out.Items, TrackingItem{Name: it.Name, Brand: it.Brand})
+	}
+	return out
+}
+
+// maskWords keeps the first letter of every word: "Ploshad Mira 15" becomes
+// "P****** M*** 1*".
+func maskWords(s string) string {
+	var sb strings.Builder
+	first := true
+	for _, c := range s {
+		switch {
+		case unicode.IsSpace(c) || unicode.IsPunct(c):
+			sb.WriteRune(c)
+			first = true
+		case first:
+			sb.WriteRune(c)
+			first = false
+		default:
+			sb.WriteByte('*')
+		}
+	}
+	return sb.String()
+}
+
+// maskPhone keeps the formatting and the last two digits: "+9720000000"
+// becomes "+********00".
+func maskPhone(s string) string {
+	digits := 0
+	for _, c := range s {
+		if unicode.IsDigit(c) {
+			digits++
+		}
+	}
+	var sb strings.Builder
+	for _, c := range s {
+		if unicode.IsDigit(c) {
+			digits--
+			if digits >= 2 {
+				c = '*'
+			}
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// trackingSvc serves TrackOrder from a map keyed by track number.
+type trackingSvc struct {
+	serviceInterface
+	byTrack map[string]order.Order
+}
+
+func (s *trackingSvc) TrackOrder(_ context.Context, track string) (order.Order, error) {
+	o, ok := s.byTrack[track]
+	if !ok {
+		return order.Order{}, orders.ErrNotFound
+	}
+	return o, nil
+}
+
+func track(t *testing.T, svc serviceInterface, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	logging.InitLogger()
+	r := chi.NewRouter()
+	r.Get("/tracking/{track_number}", NewOrderHandlers(svc, BulkLimits{}).TrackingHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestTrackingMasksRecipient(t *testing.T) {
+	o := order.Order{
+		OrderUID: "b563feb7b2b84b6test", TrackNumber: "WBILMTESTTRACK", CustomerId: "test",
+		DeliveryService: "meest", Status: order.StatusShipped,
+		Delivery: order.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin",
+			Region: "Kraiot", Address: "Ploshad Mira 15", Email: "test@gmail.com", Zip: "2639809"},
+		Payment: order.Payment{Transaction: "b563feb7b2b84b6test", Amount: 1817},
+		Items:   []order.Item{{ChrtId: "9934930", Name: "Mascaras", Brand: "Vivienne Sabo"}},
+	}
+	w := track(t, &trackingSvc{byTrack: map[string]order.Order{o.TrackNumber: o}}, "/tracking/WBILMTESTTRACK")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp TrackingResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "shipped", resp.Status)
+	assert.Equal(t, "meest", resp.DeliveryService)
+	assert.Equal(t, TrackingDelivery{Name: "T*** T*****", Phone: "+********00", City: "Kiryat Mozkin",
+		Region: "Kraiot", Address: "P****** M*** 1*"}, resp.Delivery)
+	assert.Equal(t, 1, resp.ItemsCount)
+	assert.Equal(t, []TrackingItem{{Name: "Mascaras", Brand: "Vivienne Sabo"}}, resp.Items)
+
+	for _, secret := range []string{"b563feb7b2b84b6test", "test@gmail.com", "2639809", "1817", "9934930"} {
+		assert.NotContains(t, w.Body.String(), secret)
+	}
+}
+
+func TestTrackingNotFound(t *testing.T) {
+	w := track(t, &trackingSvc{}, "/tracking/UNKNOWN")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "/problems/not-found")
+}
+
+func TestMaskPhone(t *testing.T) {
+	assert.Equal(t, "+* (***) ***-**-67", maskPhone("+7 (912) 345-45-67"))
+	assert.Equal(t, "7", maskPhone("7"))
+	assert.Equal(t, "", maskPhone(""))
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+	"github.com/sirupsen/logrus"
+	"net/http"
+)
+
+type TransitionRequest struct {
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+}
+
+type StatusChangeResponse struct {
+	OrderUID  string `json:"order_uid"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Reason    string `json:"reason,omitempty"`
+	ChangedAt string `json:"changed_at"`
+}
+
+func toStatusChangeResponse(ch order.StatusChange) StatusChangeResponse {
+	return StatusChangeResponse{
+		OrderUID:  ch.OrderUID,
+		From:      string(ch.From),
+		To:        string(ch.To),
+		Reason:    ch.Reason,
+		ChangedAt: ch.ChangedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+func (h *OrderHandlers) TransitionHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		logging.LogError("ID is required in TransitionHandler", nil, logrus.Fields{"method": "TransitionHandler"})
+		writeProblem(w, r, probBadRequest, "id is required")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<16)
+	defer r.Body.Close()
+
+	var req TransitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logging.LogError("Error decoding request body", err, logrus.Fields{"method": "TransitionHandler", "id": id})
+		writeBodyErr(w, r, err)
+		return
+	}
+	to, err := order.ParseStatus(req.To)
+	if err != nil {
+		logging.LogError("Unknown target status", err, logrus.Fields{"method": "TransitionHandler", "id": id})
+		writeErr(w, r, err)
+		return
+	}
+
+	ch, err := h.svc.TransitionOrder(r.Context(), id, to, req.Reason)
+	if err != nil {
+		writeErr(w, r, err)
+		logging.LogError("Error transitioning order", err, logrus.Fields{"method": "TransitionHandler", "id": id})
+		return
+	}
+
+	logging.LogInfo("Order transitioned", logrus.Fields{"method": "TransitionHandler", "id": id, "to": ch.To})
+	writeJSON(w, http.StatusOK, toStatusChangeResponse(ch))
+}
+
+func (h *OrderHandlers) TransitionHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		logging.LogError("ID is required in TransitionHistoryHandler", nil, logrus.Fields{"method": "TransitionHistoryHandler"})
+		writeProblem(w, r, probBadRequest, "id is required")
+		return
+	}
+
+	list, err := h.svc.StatusHistory(r.Context(), id)
+	if err != nil {
+		logging.LogError("Error fetching status history", err, logrus.Fields{"method": "TransitionHistoryHandler", "id": id})
+		writeErr(w, r, err)
+		return
+	}
+
+	out := make([]StatusChangeResponse, 0, len(list))
+	for _, ch := range list {
+		out = append(out, toStatusChangeResponse(ch))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
@@ -8,10 +8,15 @@ import (
 	"time"
 
 	kgo "github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
 )
 
 type Message struct {
 	Topic    string
+	GroupID  string
 	Key      []byte
 	Headers  map[string]string
 	Raw      kgo.Message
@@ -40,8 +45,16 @@ type ConsumerConfig struct {
 
 	PerMessageTimeout time.Duration
 
+	// RawPayload disables envelope validation for topics carrying plain
+	// documents instead of Envelope-wrapped events.
+	RawPayload bool
+
 	DLQTopic    string
 	DLQProducer Producer
+
+	// OffsetStore enables deduplication: messages at or below the stored
+	// offset are skipped, and processed offsets are recorded after handling.
+	OffsetStore orders.OffsetStore
 }
 
 type readerConsumer struct {
@@ -83,14 +96,23 @@ func (c *readerConsumer) Subscribe(ctx context.Context, topic string, groupID st
 		}
 
 		msg := toMessage(topic, m)
+		msg.GroupID = groupID
+		off := orders.ConsumerOffset{Topic: topic, Partition: m.Partition, GroupID: groupID, Offset: m.Offset}
+
+		if c.alreadyProcessed(ctx, off) {
+			_ = r.CommitMessages(ctx, m)
+			continue
+		}
 
-		if msg.Envelope.EventType == "" {
+		if !c.cfg.RawPayload && msg.Envelope.EventType == "" {
 			_ = c.pushDLQ(ctx, msg, fmt.Errorf("empty event_type"))
+			c.saveOffset(ctx, off)
 			_ = r.CommitMessages(ctx, m)
 			continue
 		}
-		if msg.Envelope.Version <= 0 {
+		if !c.cfg.RawPayload && msg.Envelope.Version <= 0 {
 			_ = c.pushDLQ(ctx, msg, fmt.Errorf("invalid version: %d", msg.Envelope.Version))
+			c.saveOffset(ctx, off)
 			_ = r.CommitMessages(ctx, m)
 			continue
 		}
@@ -101,10 +123,13 @@ func (c *readerConsumer) Subscribe(ctx context.Context, topic string, groupID st
 				return nil
 			}
 			msgCtx, cancel := context.WithTimeout(ctx, c.cfg.PerMessageTimeout)
+			if c.cfg.OffsetStore != nil {
+				msgCtx = orders.WithConsumerOffset(msgCtx, off)
+			}
 			hErr = safeHandle(msgCtx, handler, msg)
 			cancel()
 
-			if hErr == nil {
+			if hErr == nil || IsPermanent(hErr) {
 				break
 			}
 			time.Sleep(c.cfg.Backoff * time.Duration(attempt+1))
@@ -112,8 +137,10 @@ func (c *readerConsumer) Subscribe(ctx context.Context, topic string, groupID st
 
 		if hErr != nil {
 			_ = c.pushDLQ(ctx, msg, hErr)
+			c.saveOffset(ctx, off)
 			continue
 		}
+		c.saveOffset(ctx, off)
 
 		if err := r.CommitMessages(ctx, m); err != nil {
 			time.Sleep(100 * time.Millisecond)
@@ -121,6 +148,38 @@ func (c *readerConsumer) Subscribe(ctx context.Context, topic string, groupID st
 	}
 }
 
+func (c *readerConsumer) alreadyProcessed(ctx context.Context, off orders.ConsumerOffset) bool {
+	if c.cfg.OffsetStore == nil {
+		return false
+	}
+	last, ok, err := c.cfg.OffsetStore.LastOffset(ctx, off.Topic, off.Partition, off.GroupID)
+	if err != nil {
+		logging.LogError("kafka consumer offset lookup failed", err, logrus.Fields{
+			"topic": off.Topic, "partition": off.Partition, "group": off.GroupID,
+		})
+		return false
+	}
+	if ok && off.Offset <= last {
+		logging.LogInfo("kafka consumer skipped redelivered message", logrus.Fields{
+			"topic": off.Topic, "partition": off.Partition, "group": off.GroupID,
+			"offset": off.Offset, "last_processed": last,
+		})
+		return true
+	}
+	return false
+}
+
+func (c *readerConsumer) saveOffset(ctx context.Context, off orders.ConsumerOffset) {
+	if c.cfg.OffsetStore == nil {
+		return
+	}
+	if err := c.cfg.OffsetStore.SaveOffset(ctx, off); err != nil {
+		logging.LogError("kafka consumer offset save failed", err, logrus.Fields{
+			"topic": off.Topic, "partition": off.Partition, "group": off.GroupID, "offset": off.Offset,
+		})
+	}
+}
+
 func (c *readerConsumer) Close() error {
 	if c.reader != nil {
 		return c.reader.Close()
@@ -144,6 +203,27 @@ func toMessage(topic string, m kgo.Message) Message {
 	}
 }
 
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as not retryable: the message goes to the
+// DLQ right away instead of after MaxRetries attempts.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var pe permanentError
+	return errors.As(err, &pe)
+}
+
 func safeHandle(ctx context.Context, h Handler, msg Message) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
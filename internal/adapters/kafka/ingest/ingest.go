@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	kaf "github.com/reybrally/order-service/internal/adapters/kafka"
+	"github.com/reybrally/order-service/internal/adapters/orderdto"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/domain/order/validation"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+type orderUpserter interface {
+	CreateOrUpdateOrder(ctx context.Context, o order.Order) (order.Order, error)
+}
+
+type OrderIngestor struct {
+	svc orderUpserter
+}
+
+func NewOrderIngestor(svc orderUpserter) *OrderIngestor {
+	return &OrderIngestor{svc: svc}
+}
+
+// Handle decodes an OrderUpsertRequest document from msg and upserts it.
+// Malformed and invalid documents are returned as permanent errors so the
+// consumer sends them to the DLQ without retrying.
+func (in *OrderIngestor) Handle(ctx context.Context, msg kaf.Message) error {
+	fields := logrus.Fields{"topic": msg.Topic, "partition": msg.Raw.Partition, "offset": msg.Raw.Offset}
+	ctx = orders.WithActor(ctx, orders.Actor{
+		Name:      "kafka:" + msg.Topic,
+		RequestID: fmt.Sprintf("%s/%d/%d", msg.Topic, msg.Raw.Partition, msg.Raw.Offset),
+		Source:    "kafka",
+	})
+
+	var req orderdto.OrderUpsertRequest
+	if err := json.Unmarshal(msg.Raw.Value, &req); err != nil {
+		logging.LogError("order-ingest bad payload", err, fields)
+		return kaf.Permanent(fmt.Errorf("decode order: %w", err))
+	}
+
+	o, err := req.ToModel()
+	if err != nil {
+		logging.LogError("order-ingest conversion failed", err, fields)
+		return kaf.Permanent(fmt.Errorf("convert order: %w", err))
+	}
+	if vs := validation.ValidateOrder(o); len(vs) > 0 {
+		fields["order_uid"] = o.OrderUID
+		fields["violations"] = vs
+		logging.LogError("order-ingest invalid order", vs, fields)
+		return kaf.Permanent(fmt.Errorf("invalid order: %w", vs))
+	}
+
+	ord, err := in.svc.CreateOrUpdateOrder(ctx, o)
+	if err != nil {
+		fields["order_uid"] = o.OrderUID
+		logging.LogError("order-ingest upsert failed", err, fields)
+		if errors.Is(err, orders.ErrInvalidData) || errors.Is(err, orders.ErrInvalidReference) || errors.Is(err, orders.ErrConflict) {
+			return kaf.Permanent(err)
+		}
+		return err
+	}
+
+	fields["order_uid"] = ord.OrderUID
+	logging.LogInfo("order-ingest upserted", fields)
+	return nil
+}
@@ -0,0 +1,110 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	segmentio "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kaf "github.com/reybrally/order-service/internal/adapters/kafka"
+	"github.com/reybrally/order-service/internal/adapters/orderdto"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// fakeUpserter fails with err, or stores the order and the actor of ctx.
+type fakeUpserter struct {
+	err   error
+	got   []order.Order
+	actor orders.Actor
+}
+
+func (f *fakeUpserter) CreateOrUpdateOrder(ctx context.Context, o order.Order) (order.Order, error) {
+	if f.err != nil {
+		return order.Order{}, f.err
+	}
+	f.got = append(f.got, o)
+	f.actor = orders.ActorFromContext(ctx)
+	return o, nil
+}
+
+func orderDocument(t *testing.T, mutate func(*orderdto.OrderUpsertRequest)) []byte {
+	t.Helper()
+	now := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	uid := "ingest-1"
+	req := orderdto.OrderUpsertRequest{
+		OrderUID: &uid, TrackNumber: "WBILMTESTTRACK", Entry: "WBIL", Locale: "en", CustomerID: "test", DeliveryService: "meest",
+		DateCreatedRFC: now,
+		Delivery: orderdto.DeliveryDTO{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+		Payment: orderdto.PaymentDTO{Transaction: "tx-ingest-1", Currency: "USD", Provider: "wbpay", Amount: 1817,
+			PaymentDtRFC: now, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []orderdto.ItemDTO{{ChrtID: "9934930", TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "rid", Name: "Mascaras",
+			Sale: 30, TotalPrice: 317, NmID: "2389212", Brand: "Vivienne Sabo"}},
+	}
+	if mutate != nil {
+		mutate(&req)
+	}
+	b, err := json.Marshal(req)
+	require.NoError(t, err)
+	return b
+}
+
+func TestOrderIngestorHandle(t *testing.T) {
+	logging.InitLogger()
+	valid := orderDocument(t, nil)
+	errConnReset := errors.New("connection reset")
+
+	tests := []struct {
+		name      string
+		value     []byte
+		repoErr   error
+		wantErr   error
+		permanent bool
+		stored    bool
+	}{
+		{name: "valid order", value: valid, stored: true},
+		{name: "undecodable payload", value: []byte(`{"order_uid": `), permanent: true},
+		{name: "wrong field type", value: []byte(`{"items": "none"}`), permanent: true},
+		{
+			name:      "invalid order",
+			value:     orderDocument(t, func(r *orderdto.OrderUpsertRequest) { r.Items = nil; r.Delivery.Email = "" }),
+			permanent: true,
+		},
+		{name: "conflict", value: valid, repoErr: orders.ErrConflict, wantErr: orders.ErrConflict, permanent: true},
+		{name: "invalid data", value: valid, repoErr: orders.ErrInvalidData, wantErr: orders.ErrInvalidData, permanent: true},
+		{name: "invalid reference", value: valid, repoErr: orders.ErrInvalidReference, wantErr: orders.ErrInvalidReference, permanent: true},
+		{name: "serialization failure", value: valid, repoErr: orders.ErrRetryable, wantErr: orders.ErrRetryable},
+		{name: "timeout", value: valid, repoErr: orders.ErrTimeout, wantErr: orders.ErrTimeout},
+		{name: "unknown error", value: valid, repoErr: fmt.Errorf("upsert: %w", errConnReset), wantErr: errConnReset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeUpserter{err: tt.repoErr}
+			msg := kaf.Message{Topic: "orders-ingest", Raw: segmentio.Message{Topic: "orders-ingest", Partition: 2, Offset: 41, Value: tt.value}}
+
+			err := NewOrderIngestor(svc).Handle(context.Background(), msg)
+
+			if tt.stored {
+				require.NoError(t, err)
+				require.Len(t, svc.got, 1)
+				assert.Equal(t, "ingest-1", svc.got[0].OrderUID)
+				assert.Equal(t, orders.Actor{Name: "kafka:orders-ingest", RequestID: "orders-ingest/2/41", Source: "kafka"}, svc.actor)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.permanent, kaf.IsPermanent(err), "permanent")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Empty(t, svc.got)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+type OutboxRelayConfig struct {
+	PollInterval  time.Duration
+	BatchSize     int
+	MaxAttempts   int
+	Backoff       time.Duration
+	SendTimeout   time.Duration
+	SentRetention time.Duration
+	// Lease is how long a claimed batch is reserved for this relay; events it
+	// has not published by then are claimed again. It is at least twice
+	// SendTimeout.
+	Lease time.Duration
+}
+
+type OutboxRelay struct {
+	store    orders.OutboxStore
+	producer Producer
+	cfg      OutboxRelayConfig
+}
+
+func NewOutboxRelay(store orders.OutboxStore, producer Producer, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 500 * time.Millisecond
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 5 * time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+	if cfg.Lease < 2*cfg.SendTimeout {
+		cfg.Lease = 2 * cfg.SendTimeout
+	}
+	return &OutboxRelay{store: store, producer: producer, cfg: cfg}
+}
+
+// NewOutboxEvent serializes env the same way PublishEnvelope does, so that the
+// relay publishes byte-identical messages later.
+func NewOutboxEvent[T any](topic string, key []byte, env Envelope[T], headers map[string]string) (orders.OutboxEvent, error) {
+	if env.OccurredAt.IsZero() {
+		env.OccurredAt = time.Now().UTC()
+	}
+	if env.Meta.Producer == "" {
+		env.Meta.Producer = "order-service"
+	}
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return orders.OutboxEvent{}, err
+	}
+	return orders.OutboxEvent{
+		AggregateID: env.EntityID,
+		Topic:       topic,
+		Key:         key,
+		EventType:   env.EventType,
+		Payload:     payload,
+		Headers:     headers,
+	}, nil
+}
+
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	logging.LogInfo("outbox relay started", logrus.Fields{
+		"poll_interval": r.cfg.PollInterval.String(), "batch_size": r.cfg.BatchSize,
+	})
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	lastPurge := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			logging.LogInfo("outbox relay stopped", logrus.Fields{})
+			return nil
+		case <-ticker.C:
+		}
+
+		for ctx.Err() == nil {
+			res, err := r.store.DispatchPending(ctx, orders.OutboxDispatchOptions{
+				Limit:       r.cfg.BatchSize,
+				MaxAttempts: r.cfg.MaxAttempts,
+				Backoff:     r.cfg.Backoff,
+				Lease:       r.cfg.Lease,
+				SendTimeout: r.cfg.SendTimeout,
+			}, r.send)
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.LogError("outbox dispatch failed", err, logrus.Fields{})
+				}
+				break
+			}
+			if res.Sent+res.Failed+res.Dead > 0 {
+				logging.LogDebug("outbox batch dispatched", logrus.Fields{
+					"sent": res.Sent, "failed": res.Failed, "dead": res.Dead,
+				})
+			}
+			if res.Sent < r.cfg.BatchSize || res.Failed+res.Dead > 0 {
+				break
+			}
+		}
+
+		if r.cfg.SentRetention > 0 && time.Since(lastPurge) >= r.cfg.SentRetention/24 {
+			lastPurge = time.Now()
+			if n, err := r.store.PurgeSent(ctx, time.Now().UTC().Add(-r.cfg.SentRetention)); err != nil {
+				logging.LogError("outbox purge failed", err, logrus.Fields{})
+			} else if n > 0 {
+				logging.LogInfo("outbox purged sent events", logrus.Fields{"deleted": n})
+			}
+		}
+	}
+}
+
+func (r *OutboxRelay) send(ctx context.Context, rec orders.OutboxRecord) error {
+	headers := make(map[string]string, len(rec.Headers)+2)
+	for k, v := range rec.Headers {
+		headers[k] = v
+	}
+	if _, ok := headers["content-type"]; !ok {
+		headers["content-type"] = "application/json"
+	}
+	headers["event-type"] = rec.EventType
+
+	sendCtx, cancel := context.WithTimeout(ctx, r.cfg.SendTimeout)
+	defer cancel()
+	return r.producer.Publish(sendCtx, rec.Topic, rec.Key, rec.Payload, headers)
+}
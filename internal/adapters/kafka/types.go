@@ -1,5 +1,7 @@
 package kafka
 
+import "time"
+
 type OrderUpserted struct {
 	OrderUID string `json:"order_uid"`
 }
@@ -7,3 +9,15 @@ type OrderUpserted struct {
 type OrderDeleted struct {
 	OrderUID string `json:"order_uid"`
 }
+
+type OrderRestored struct {
+	OrderUID string `json:"order_uid"`
+}
+
+type OrderStatusChanged struct {
+	OrderUID  string    `json:"order_uid"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
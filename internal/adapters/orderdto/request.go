@@ -0,0 +1,217 @@
+package orderdto
+
+import (
+	"errors"
+	"time"
+
+	"github.com/reybrally/order-service/internal/domain/order"
+)
+
+type OrderUpsertRequest struct {
+	OrderUID        *string `json:"order_uid,omitempty"`
+	TrackNumber     string  `json:"track_number"`
+	Entry           string  `json:"entry"`
+	Locale          string  `json:"locale"`
+	CustomerID      string  `json:"customer_id"`
+	DeliveryService string  `json:"delivery_service"`
+	ShardKey        string  `json:"shard_key"`
+	SmID            int64   `json:"sm_id"`
+	OofShard        int64   `json:"oof_shard"`
+
+	DateCreatedRFC string `json:"date_created,omitempty"`
+
+	Delivery DeliveryDTO `json:"delivery"`
+	Payment  PaymentDTO  `json:"payment"`
+	Items    []ItemDTO   `json:"items"`
+}
+
+type DeliveryDTO struct {
+	Name    string `json:"name"`
+	Phone   string `json:"phone"`
+	Zip     string `json:"zip"`
+	City    string `json:"city"`
+	Address string `json:"address"`
+	Region  string `json:"region"`
+	Email   string `json:"email"`
+}
+
+type PaymentDTO struct {
+	Transaction  string `json:"transaction"`
+	RequestID    string `json:"request_id"`
+	Currency     string `json:"currency"`
+	Provider     string `json:"provider"`
+	Amount       int64  `json:"amount"`
+	PaymentDtRFC string `json:"payment_dt"`
+	Bank         string `json:"bank"`
+	DeliveryCost int64  `json:"delivery_cost"`
+	GoodsTotal   int64  `json:"goods_total"`
+	CustomFee    int64  `json:"custom_fee"`
+}
+
+type ItemDTO struct {
+	ChrtID      string `json:"chrt_id"`
+	TrackNumber string `json:"track_number"`
+	Price       int64  `json:"price"`
+	Rid         string `json:"rid"`
+	Name        string `json:"item_name"`
+	Sale        int64  `json:"sale"`
+	Size        int64  `json:"item_size"`
+	TotalPrice  int64  `json:"total_price"`
+	NmID        string `json:"nm_id"`
+	Brand       string `json:"brand"`
+	Status      int64  `json:"status"`
+}
+
+func (r OrderUpsertRequest) ToModel() (order.Order, error) {
+	if r.TrackNumber == "" || r.Entry == "" || r.Locale == "" || r.CustomerID == "" || r.DeliveryService == "" {
+		return order.Order{}, errors.New("missing required fields")
+	}
+
+	var payTime time.Time
+	if r.Payment.PaymentDtRFC != "" {
+		t, err := time.Parse(time.RFC3339, r.Payment.PaymentDtRFC)
+		if err != nil {
+			return order.Order{}, errors.New("invalid payment_dt format (want RFC3339)")
+		}
+		payTime = t
+	}
+
+	var createdAt time.Time
+	if r.DateCreatedRFC != "" {
+		t, err := time.Parse(time.RFC3339, r.DateCreatedRFC)
+		if err != nil {
+			return order.Order{}, errors.New("invalid date_created (want RFC3339)")
+		}
+		createdAt = t
+	} else {
+		createdAt = time.Now().UTC()
+	}
+
+	out := order.Order{
+		OrderUID:          derefStr(r.OrderUID),
+		TrackNumber:       r.TrackNumber,
+		Entry:             r.Entry,
+		Locale:            r.Locale,
+		InternalSignature: "",
+		CustomerId:        r.CustomerID,
+		DeliveryService:   r.DeliveryService,
+		ShardKey:          r.ShardKey,
+		SmId:              r.SmID,
+		OofShard:          r.OofShard,
+
+		Delivery: order.Delivery{
+			Name:    r.Delivery.Name,
+			Phone:   r.Delivery.Phone,
+			Zip:     r.Delivery.Zip,
+			City:    r.Delivery.City,
+			Address: r.Delivery.Address,
+			Region:  r.Delivery.Region,
+			Email:   r.Delivery.Email,
+		},
+		Payment: order.Payment{
+			Transaction:  r.Payment.Transaction,
+			RequestId:    r.Payment.RequestID,
+			Currency:     r.Payment.Currency,
+			Provider:     r.Payment.Provider,
+			Amount:       r.Payment.Amount,
+			PaymentDt:    payTime,
+			Bank:         r.Payment.Bank,
+			DeliveryCost: r.Payment.DeliveryCost,
+			GoodsTotal:   r.Payment.GoodsTotal,
+			CustomFee:    r.Payment.CustomFee,
+		},
+		DateCreated: createdAt,
+		Items:       make([]order.Item, 0, len(r.Items)),
+	}
+
+	for _, it := range r.Items {
+		out.Items = append(out.Items, order.Item{
+			ChrtId:      it.ChrtID,
+			TrackNumber: it.TrackNumber,
+			Price:       it.Price,
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        it.Sale,
+			Size:        it.Size,
+			TotalPrice:  it.TotalPrice,
+			NmId:        it.NmID,
+			Brand:       it.Brand,
+			Status:      it.Status,
+		})
+	}
+
+	return out, nil
+}
+
+func derefStr(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// FromModel is the inverse of ToModel: the request that would store o.
+// Timestamps keep their full precision so that a round trip does not change
+// them.
+func FromModel(o order.Order) OrderUpsertRequest {
+	uid := o.OrderUID
+	out := OrderUpsertRequest{
+		OrderUID:        &uid,
+		TrackNumber:     o.TrackNumber,
+		Entry:           o.Entry,
+		Locale:          o.Locale,
+		CustomerID:      o.CustomerId,
+		DeliveryService: o.DeliveryService,
+		ShardKey:        o.ShardKey,
+		SmID:            o.SmId,
+		OofShard:        o.OofShard,
+		DateCreatedRFC:  formatTime(o.DateCreated),
+
+		Delivery: DeliveryDTO{
+			Name:    o.Delivery.Name,
+			Phone:   o.Delivery.Phone,
+			Zip:     o.Delivery.Zip,
+			City:    o.Delivery.City,
+			Address: o.Delivery.Address,
+			Region:  o.Delivery.Region,
+			Email:   o.Delivery.Email,
+		},
+		Payment: PaymentDTO{
+			Transaction:  o.Payment.Transaction,
+			RequestID:    o.Payment.RequestId,
+			Currency:     o.Payment.Currency,
+			Provider:     o.Payment.Provider,
+			Amount:       o.Payment.Amount,
+			PaymentDtRFC: formatTime(o.Payment.PaymentDt),
+			Bank:         o.Payment.Bank,
+			DeliveryCost: o.Payment.DeliveryCost,
+			GoodsTotal:   o.Payment.GoodsTotal,
+			CustomFee:    o.Payment.CustomFee,
+		},
+		Items: make([]ItemDTO, 0, len(o.Items)),
+	}
+
+	for _, it := range o.Items {
+		out.Items = append(out.Items, ItemDTO{
+			ChrtID:      it.ChrtId,
+			TrackNumber: it.TrackNumber,
+			Price:       it.Price,
+			Rid:         it.Rid,
+			Name:        it.Name,
+			Sale:        it.Sale,
+			Size:        it.Size,
+			TotalPrice:  it.TotalPrice,
+			NmID:        it.NmId,
+			Brand:       it.Brand,
+			Status:      it.Status,
+		})
+	}
+	return out
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
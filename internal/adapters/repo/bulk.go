@@ -0,0 +1,243 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// qTrimItems removes the items of an order that are not in $2; an empty $2
+// removes them all.
+const qTrimItems = `
+DELETE FROM order_items
+WHERE order_uid = $1
+  AND NOT (chrt_id = ANY($2::text[]))`
+
+// recordError marks the record of a batch that the database rejected.
+type recordError struct {
+	index int
+	err   error
+}
+
+func (e *recordError) Error() string { return e.err.Error() }
+func (e *recordError) Unwrap() error { return e.err }
+
+// CreateOrUpdateOrders writes the records batch by batch, each batch sent to
+// Postgres as two pgx.Batch round trips: the order rows, then revisions and
+// outbox events. Without opts.Atomic every batch has its own transaction, and
+// a record that fails is dropped from its batch and the batch written again.
+// With it one transaction spans all batches and the first failing record
+// rolls everything back; the other records then fail with
+// orders.ErrBulkAborted.
+func (r *OrderRepo) CreateOrUpdateOrders(ctx context.Context, records []orders.BulkRecord, opts orders.BulkOptions) ([]orders.BulkResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	results := make([]orders.BulkResult, len(records))
+	if len(records) == 0 {
+		return results, nil
+	}
+
+	all := make([]int, len(records))
+	for i := range all {
+		all[i] = i
+	}
+	batches := slices.Collect(slices.Chunk(all, opts.BatchSize))
+
+	if opts.Atomic {
+		err := pgx.BeginFunc(ctx, r.repo, func(tx pgx.Tx) error {
+			for _, batch := range batches {
+				if err := upsertBatch(ctx, tx, records, batch, results); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			logging.LogInfo("Bulk import committed", logrus.Fields{"count": len(records), "atomic": true})
+			return results, nil
+		}
+		var rec *recordError
+		if errors.As(err, &rec) && isRecordErr(rec.err) {
+			for i := range results {
+				results[i] = orders.BulkResult{Err: orders.ErrBulkAborted}
+			}
+			results[rec.index].Err = rec.err
+			logging.LogError("Bulk import rolled back", rec.err, logrus.Fields{"count": len(records), "index": rec.index})
+			return results, nil
+		}
+		err = bulkError(ctx, err)
+		for i := range results {
+			results[i] = orders.BulkResult{Err: err}
+		}
+		logging.LogError("Bulk import failed", err, logrus.Fields{"count": len(records), "atomic": true})
+		return results, err
+	}
+
+	var failed int
+	for n, batch := range batches {
+		for len(batch) > 0 {
+			err := pgx.BeginFunc(ctx, r.repo, func(tx pgx.Tx) error {
+				return upsertBatch(ctx, tx, records, batch, results)
+			})
+			if err == nil {
+				break
+			}
+			var rec *recordError
+			if errors.As(err, &rec) && isRecordErr(rec.err) {
+				logging.LogError("Bulk import record rejected", rec.err, logrus.Fields{"index": rec.index, "order_uid": records[rec.index].Order.OrderUID})
+				results[rec.index] = orders.BulkResult{Err: rec.err}
+				batch = slices.DeleteFunc(batch, func(i int) bool { return i == rec.index })
+				failed++
+				continue
+			}
+			err = bulkError(ctx, err)
+			for _, rest := range batches[n:] {
+				for _, i := range rest {
+					if results[i].Err == nil {
+						results[i] = orders.BulkResult{Err: err}
+					}
+				}
+			}
+			logging.LogError("Bulk import stopped", err, logrus.Fields{"count": len(records), "batch": n})
+			return results, err
+		}
+	}
+	logging.LogInfo("Bulk import finished", logrus.Fields{"count": len(records), "failed": failed})
+	return results, nil
+}
+
+// upsertBatch writes records[i] for every i in batch inside tx and stores the
+// outcome in results[i]. A statement error is returned as a *recordError
+// naming the record it belongs to.
+func upsertBatch(ctx context.Context, tx pgx.Tx, records []orders.BulkRecord, batch []int, results []orders.BulkResult) error {
+	rows := make([]OrderRow, len(batch))
+	inserted := make([]bool, len(batch))
+
+	b := &pgx.Batch{}
+	for k, i := range batch {
+		o := records[i].Order
+		b.Queue(qOrders,
+			o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerId,
+			o.DeliveryService, o.ShardKey, o.SmId, o.OofShard, o.Version,
+		).Fn = func(br pgx.BatchResults) error {
+			row := &rows[k]
+			err := br.QueryRow().Scan(
+				&row.OrderUID, &row.TrackNumber, &row.Entry, &row.Locale,
+				&row.InternalSignature, &row.CustomerId, &row.DeliveryService,
+				&row.ShardKey, &row.SmId, &row.DateCreated, &row.OofShard,
+				&row.Status, &row.Version, &inserted[k],
+			)
+			switch {
+			case errors.Is(err, pgx.ErrNoRows):
+				// Version mismatch or soft-deleted, as in CreateOrUpdateOrder.
+				err = orders.ErrConflict
+			case err == nil && inserted[k] && o.Version != 0:
+				err = orders.ErrConflict
+			}
+			return recordErr(i, err)
+		}
+		queueRecord(b, i, qDelivery,
+			o.OrderUID,
+			o.Delivery.Name, o.Delivery.Phone, o.Delivery.Zip, o.Delivery.City,
+			o.Delivery.Address, o.Delivery.Region, o.Delivery.Email,
+		)
+		queueRecord(b, i, qPayment,
+			o.OrderUID, o.Payment.Transaction, o.Payment.RequestId, o.Payment.Currency,
+			o.Payment.Provider, o.Payment.Amount, o.Payment.PaymentDt, o.Payment.Bank,
+			o.Payment.DeliveryCost, o.Payment.GoodsTotal, o.Payment.CustomFee,
+		)
+		ids := make([]string, 0, len(o.Items))
+		for _, it := range o.Items {
+			queueRecord(b, i, qItem,
+				o.OrderUID, it.ChrtId, it.TrackNumber, it.Price, it.Rid, it.Name,
+				it.Sale, it.Size, it.TotalPrice, it.NmId, it.Brand, it.Status,
+			)
+			ids = append(ids, it.ChrtId)
+		}
+		queueRecord(b, i, qTrimItems, o.OrderUID, ids)
+	}
+	if err := tx.SendBatch(ctx, b).Close(); err != nil {
+		return err
+	}
+
+	b = &pgx.Batch{}
+	for k, i := range batch {
+		out := rows[k].ToDomain()
+		in := records[i].Order
+		out.Delivery, out.Payment, out.Items = in.Delivery, in.Payment, in.Items
+
+		op := order.RevisionUpdate
+		if inserted[k] {
+			op = order.RevisionCreate
+		}
+		args, err := revisionArgs(ctx, op, out)
+		if err != nil {
+			return err
+		}
+		queueRecord(b, i, qInsertRevision, args...)
+		for _, ev := range records[i].Events {
+			args, err := outboxArgs(ev)
+			if err != nil {
+				return err
+			}
+			queueRecord(b, i, qInsertOutbox, args...)
+		}
+		results[i] = orders.BulkResult{Order: out, Created: inserted[k]}
+	}
+	return tx.SendBatch(ctx, b).Close()
+}
+
+// queueRecord queues a statement of records[index] whose error is reported as
+// a *recordError.
+func queueRecord(b *pgx.Batch, index int, sql string, args ...any) {
+	b.Queue(sql, args...).Fn = func(br pgx.BatchResults) error {
+		_, err := br.Exec()
+		return recordErr(index, err)
+	}
+}
+
+// recordErr maps constraint violations the way CreateOrUpdateOrder does and
+// ties them to the record; other errors are returned as they are.
+func recordErr(index int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) {
+		switch pgerr.Code {
+		case "23503":
+			err = orders.ErrInvalidReference
+		case "23514", "23502", "22001", "22P02":
+			err = orders.ErrInvalidData
+		case "23505":
+			err = orders.ErrConflict
+		case "40001", "40P01":
+			return orders.ErrRetryable
+		default:
+			return err
+		}
+	}
+	return &recordError{index: index, err: err}
+}
+
+// isRecordErr tells errors caused by the record itself, which fail only that
+// record, from those that stop the import.
+func isRecordErr(err error) bool {
+	return errors.Is(err, orders.ErrConflict) || errors.Is(err, orders.ErrInvalidData) || errors.Is(err, orders.ErrInvalidReference)
+}
+
+func bulkError(ctx context.Context, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		return orders.ErrTimeout
+	}
+	return err
+}
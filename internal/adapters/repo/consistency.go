@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// qInconsistentOrders mirrors order.CheckConsistency in SQL so that only the
+// offending orders are loaded.
+const qInconsistentOrders = `
+WITH item_totals AS (
+  SELECT order_uid,
+         SUM(total_price)                     AS goods,
+         bool_or(total_price <> price - sale) AS bad_item
+  FROM order_items
+  GROUP BY order_uid
+)
+SELECT o.order_uid
+FROM orders o
+LEFT JOIN item_totals it ON it.order_uid = o.order_uid
+LEFT JOIN payments p ON p.order_uid = o.order_uid
+WHERE o.deleted_at IS NULL
+  AND o.order_uid > $1
+  AND (
+    COALESCE(it.bad_item, false)
+    OR (p.order_uid IS NOT NULL AND (
+         p.goods_total <> COALESCE(it.goods, 0)
+      OR p.amount <> p.goods_total + p.delivery_cost + p.custom_fee
+    ))
+  )
+ORDER BY o.order_uid
+LIMIT $2`
+
+// InconsistentOrders returns live orders whose totals do not add up, ordered
+// by order_uid and starting after the given one.
+func (r *OrderRepo) InconsistentOrders(ctx context.Context, after string, limit int) (orders.Page, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	rows, err := r.repo.Query(ctx, qInconsistentOrders, after, limit+1)
+	if err != nil {
+		return orders.Page{}, loadError(ctx, "Error querying inconsistent orders", err, limit)
+	}
+	defer rows.Close()
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return orders.Page{}, loadError(ctx, "Error scanning inconsistent order uid", err, limit)
+		}
+		uids = append(uids, uid)
+	}
+	if err := rows.Err(); err != nil {
+		return orders.Page{}, loadError(ctx, "Error iterating over inconsistent orders", err, limit)
+	}
+
+	var page orders.Page
+	if len(uids) > limit {
+		uids = uids[:limit]
+		page.NextCursor = uids[limit-1]
+	}
+
+	page.Orders, err = loadOrders(ctx, r.repo, uids)
+	if err != nil {
+		return orders.Page{}, err
+	}
+	logging.LogInfo("Inconsistent orders loaded", logrus.Fields{"count": len(page.Orders), "after": after})
+	return page, nil
+}
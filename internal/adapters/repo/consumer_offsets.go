@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	qLastConsumerOffset = `
+SELECT "offset" FROM consumer_offsets
+WHERE topic = $1 AND "partition" = $2 AND group_id = $3;`
+
+	qSaveConsumerOffset = `
+INSERT INTO consumer_offsets (topic, "partition", group_id, "offset")
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (topic, "partition", group_id) DO UPDATE SET
+  "offset"     = GREATEST(consumer_offsets."offset", EXCLUDED."offset"),
+  processed_at = now();`
+)
+
+type ConsumerOffsetRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewConsumerOffsetRepo(pool *pgxpool.Pool) *ConsumerOffsetRepo {
+	return &ConsumerOffsetRepo{pool: pool}
+}
+
+func (r *ConsumerOffsetRepo) LastOffset(ctx context.Context, topic string, partition int, groupID string) (int64, bool, error) {
+	var off int64
+	err := r.pool.QueryRow(ctx, qLastConsumerOffset, topic, partition, groupID).Scan(&off)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		logging.LogError("Error reading consumer offset", err, logrus.Fields{
+			"topic": topic, "partition": partition, "group": groupID,
+		})
+		return 0, false, err
+	}
+	return off, true, nil
+}
+
+func (r *ConsumerOffsetRepo) SaveOffset(ctx context.Context, off orders.ConsumerOffset) error {
+	if _, err := r.pool.Exec(ctx, qSaveConsumerOffset, off.Topic, off.Partition, off.GroupID, off.Offset); err != nil {
+		logging.LogError("Error saving consumer offset", err, logrus.Fields{
+			"topic": off.Topic, "partition": off.Partition, "group": off.GroupID, "offset": off.Offset,
+		})
+		return err
+	}
+	return nil
+}
+
+// saveContextOffset stores the consumer offset carried by ctx, if any, inside tx.
+func saveContextOffset(ctx context.Context, tx pgx.Tx) error {
+	off, ok := orders.ConsumerOffsetFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, qSaveConsumerOffset, off.Topic, off.Partition, off.GroupID, off.Offset); err != nil {
+		logging.LogError("Error saving consumer offset in transaction", err, logrus.Fields{
+			"topic": off.Topic, "partition": off.Partition, "group": off.GroupID, "offset": off.Offset,
+		})
+		return err
+	}
+	return nil
+}
@@ -27,10 +27,13 @@ ON CONFLICT (order_uid) DO UPDATE SET
     delivery_service   = EXCLUDED.delivery_service,
     shard_key          = EXCLUDED.shard_key,
     sm_id              = EXCLUDED.sm_id,
-    oof_shard          = EXCLUDED.oof_shard
+    oof_shard          = EXCLUDED.oof_shard,
+    version            = orders.version + 1
+WHERE orders.deleted_at IS NULL AND ($11::bigint <= 0 OR orders.version = $11::bigint)
 RETURNING
     order_uid, track_number, entry, locale, internal_signature, customer_id,
-    delivery_service, shard_key, sm_id, date_created, oof_shard;`
+    delivery_service, shard_key, sm_id, date_created, oof_shard, status, version,
+    (xmax = 0) AS inserted;`
 
 	qDelivery = `
 INSERT INTO deliveries (
@@ -81,10 +84,8 @@ ON CONFLICT (order_uid) DO UPDATE SET
 RETURNING transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee;`
 )
 
-func (r *OrderRepo) CreateOrUpdateOrder(ctx context.Context, o order.Order) (order.Order, error) {
+func (r *OrderRepo) CreateOrUpdateOrder(ctx context.Context, o order.Order, events ...orders.OutboxEvent) (order.Order, error) {
 	logging.LogInfo("Attempting to create or update order", logrus.Fields{"order_uid": o.OrderUID})
-	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	tx, err := r.repo.Begin(ctx)
 	if err != nil {
@@ -93,19 +94,29 @@ func (r *OrderRepo) CreateOrUpdateOrder(ctx context.Context, o order.Order) (ord
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	var orderRow OrderRow
+	var (
+		orderRow OrderRow
+		inserted bool
+	)
+	// The upsert locks the order row (or the new key) until commit, which
+	// serializes concurrent writers of the same order for the child tables
+	// and the revision number below.
 	if err := tx.QueryRow(ctx, qOrders,
 		o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerId,
-		o.DeliveryService, o.ShardKey, o.SmId, o.OofShard,
+		o.DeliveryService, o.ShardKey, o.SmId, o.OofShard, o.Version,
 	).Scan(
 		&orderRow.OrderUID, &orderRow.TrackNumber, &orderRow.Entry, &orderRow.Locale,
 		&orderRow.InternalSignature, &orderRow.CustomerId, &orderRow.DeliveryService,
 		&orderRow.ShardKey, &orderRow.SmId, &orderRow.DateCreated, &orderRow.OofShard,
+		&orderRow.Status, &orderRow.Version, &inserted,
 	); err != nil {
 		logging.LogError("Error executing order query", err, logrus.Fields{"order_uid": o.OrderUID})
 
 		if errors.Is(err, pgx.ErrNoRows) {
-			return order.Order{}, orders.ErrUnexpected
+			// The upsert only skips the update when the version does not match
+			// or the order is soft-deleted and has to be restored first.
+			logging.LogError("Order version mismatch or order is deleted", err, logrus.Fields{"order_uid": o.OrderUID, "expected_version": o.Version})
+			return order.Order{}, orders.ErrConflict
 		}
 		var pgerr *pgconn.PgError
 		if errors.As(err, &pgerr) {
@@ -121,6 +132,11 @@ func (r *OrderRepo) CreateOrUpdateOrder(ctx context.Context, o order.Order) (ord
 		return order.Order{}, err
 	}
 
+	if inserted && o.Version != 0 {
+		logging.LogError("Expected version given for a new order", nil, logrus.Fields{"order_uid": o.OrderUID, "expected_version": o.Version})
+		return order.Order{}, orders.ErrConflict
+	}
+
 	var deliveryRow DeliveryRow
 	if err := tx.QueryRow(ctx, qDelivery,
 		o.OrderUID,
@@ -264,11 +280,6 @@ func (r *OrderRepo) CreateOrUpdateOrder(ctx context.Context, o order.Order) (ord
 		}
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		logging.LogError("Error committing transaction", err, logrus.Fields{"order_uid": o.OrderUID})
-		return order.Order{}, err
-	}
-
 	out := orderRow.ToDomain()
 
 	out.Delivery = order.Delivery{
@@ -312,6 +323,25 @@ func (r *OrderRepo) CreateOrUpdateOrder(ctx context.Context, o order.Order) (ord
 		})
 	}
 
+	op := order.RevisionUpdate
+	if inserted {
+		op = order.RevisionCreate
+	}
+	if err := insertRevision(ctx, tx, op, out); err != nil {
+		return order.Order{}, err
+	}
+	if err := insertOutbox(ctx, tx, events); err != nil {
+		return order.Order{}, err
+	}
+	if err := saveContextOffset(ctx, tx); err != nil {
+		return order.Order{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.LogError("Error committing transaction", err, logrus.Fields{"order_uid": o.OrderUID})
+		return order.Order{}, err
+	}
+
 	logging.LogInfo("Order successfully created or updated", logrus.Fields{"order_uid": o.OrderUID})
 
 	return out, nil
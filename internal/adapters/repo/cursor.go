@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+)
+
+type sortKind int
+
+const (
+	sortText sortKind = iota
+	sortTime
+	sortInt
+	sortFloat
+)
+
+type sortColumn struct {
+	expr string
+	kind sortKind
+}
+
+// searchCursor is the keyset position after the last returned row: the value
+// of the sort column and the order_uid tie-breaker.
+type searchCursor struct {
+	SortBy  string `json:"s"`
+	SortDir string `json:"d"`
+	Value   string `json:"v"`
+	UID     string `json:"u"`
+}
+
+func encodeCursor(c searchCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses token and checks that it was issued for the same sort.
+func decodeCursor(token, sortBy, sortDir string) (searchCursor, error) {
+	var c searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, orders.ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.UID == "" {
+		return searchCursor{}, orders.ErrInvalidCursor
+	}
+	if c.SortBy != sortBy || c.SortDir != sortDir {
+		return searchCursor{}, orders.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+func formatSortValue(kind sortKind, v any) string {
+	switch kind {
+	case sortTime:
+		if t, ok := v.(time.Time); ok {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	case sortInt:
+		if n, ok := v.(int64); ok {
+			return strconv.FormatInt(n, 10)
+		}
+	case sortFloat:
+		if f, ok := v.(float64); ok {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	default:
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func parseSortValue(kind sortKind, s string) (any, error) {
+	switch kind {
+	case sortTime:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, orders.ErrInvalidCursor
+		}
+		return t, nil
+	case sortInt:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, orders.ErrInvalidCursor
+		}
+		return n, nil
+	case sortFloat:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, orders.ErrInvalidCursor
+		}
+		return f, nil
+	default:
+		return s, nil
+	}
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	ts := time.Date(2021, 11, 26, 6, 22, 19, 123456000, time.UTC)
+	token := encodeCursor(searchCursor{
+		SortBy: "date_created", SortDir: "desc",
+		Value: formatSortValue(sortTime, ts), UID: "uid-1",
+	})
+
+	c, err := decodeCursor(token, "date_created", "desc")
+	require.NoError(t, err)
+	assert.Equal(t, "uid-1", c.UID)
+
+	v, err := parseSortValue(sortTime, c.Value)
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(v.(time.Time)))
+}
+
+func TestSortValueKinds(t *testing.T) {
+	v, err := parseSortValue(sortInt, formatSortValue(sortInt, int64(1817)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1817), v)
+
+	v, err = parseSortValue(sortText, formatSortValue(sortText, "WBILMTESTTRACK"))
+	require.NoError(t, err)
+	assert.Equal(t, "WBILMTESTTRACK", v)
+
+	// Relevance scores must survive the round trip bit for bit, otherwise
+	// the keyset comparison would skip or repeat rows.
+	score := 0.1 + 0.2
+	v, err = parseSortValue(sortFloat, formatSortValue(sortFloat, score))
+	require.NoError(t, err)
+	assert.Equal(t, score, v)
+
+	_, err = parseSortValue(sortInt, "abc")
+	assert.ErrorIs(t, err, orders.ErrInvalidCursor)
+	_, err = parseSortValue(sortFloat, "abc")
+	assert.ErrorIs(t, err, orders.ErrInvalidCursor)
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	token := encodeCursor(searchCursor{SortBy: "amount", SortDir: "asc", Value: "10", UID: "u"})
+
+	cases := map[string]struct{ token, sortBy, sortDir string }{
+		"garbage":      {"not base64!", "amount", "asc"},
+		"not json":     {"bm90IGpzb24", "amount", "asc"},
+		"other column": {token, "date_created", "asc"},
+		"other dir":    {token, "amount", "desc"},
+		"missing uid":  {encodeCursor(searchCursor{SortBy: "amount", SortDir: "asc"}), "amount", "asc"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeCursor(tc.token, tc.sortBy, tc.sortDir)
+			assert.ErrorIs(t, err, orders.ErrInvalidCursor)
+		})
+	}
+}
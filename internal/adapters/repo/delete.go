@@ -4,16 +4,23 @@ import (
 	"context"
 	"errors"
 	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
 	"github.com/reybrally/order-service/internal/logging"
 	"github.com/sirupsen/logrus"
+	"time"
 )
 
-const qDeleteOrder = `DELETE FROM orders WHERE order_uid = $1;`
+const (
+	qSoftDeleteOrder = `UPDATE orders SET deleted_at = now(), version = version + 1 WHERE order_uid = $1;`
 
-func (r *OrderRepo) DeleteOrder(ctx context.Context, uid string) error {
+	qRestoreOrder = `UPDATE orders SET deleted_at = NULL, version = version + 1 WHERE order_uid = $1;`
+
+	qPurgeDeletedOrders = `DELETE FROM orders WHERE deleted_at IS NOT NULL AND deleted_at < $1;`
+)
+
+// DeleteOrder only marks the order as deleted; PurgeDeleted removes it for good.
+func (r *OrderRepo) DeleteOrder(ctx context.Context, uid string, events ...orders.OutboxEvent) error {
 	logging.LogInfo("Attempting to delete order", logrus.Fields{"order_uid": uid})
-	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	select {
 	case <-ctx.Done():
@@ -21,21 +28,125 @@ func (r *OrderRepo) DeleteOrder(ctx context.Context, uid string) error {
 		return orders.ErrTimeout
 	default:
 	}
-	ct, err := r.repo.Exec(ctx, qDeleteOrder, uid)
+
+	tx, err := r.repo.Begin(ctx)
+	if err != nil {
+		logging.LogError("Error starting transaction", err, logrus.Fields{"order_uid": uid})
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	locked, err := lockOrder(ctx, tx, uid)
+	if err == nil && locked.DeletedAt != nil {
+		err = orders.ErrNotFound
+	}
 	if err != nil {
-		logging.LogError("Error executing DELETE query", err, logrus.Fields{"order_uid": uid})
+		if errors.Is(err, orders.ErrNotFound) {
+			logging.LogError("Order not found to delete", nil, logrus.Fields{"order_uid": uid})
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, qSoftDeleteOrder, uid); err != nil {
+		logging.LogError("Error executing soft delete query", err, logrus.Fields{"order_uid": uid})
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
 			logging.LogError("Context canceled or deadline exceeded during DELETE", err, logrus.Fields{"order_uid": uid})
 			return orders.ErrTimeout
 		}
 		return err
 	}
-	if ct.RowsAffected() == 0 {
-		logging.LogError("Order not found to delete", nil, logrus.Fields{"order_uid": uid})
-		return orders.ErrNotFound
+
+	snapshot, err := getOrder(ctx, tx, uid)
+	if err != nil {
+		logging.LogError("Error loading order snapshot after delete", err, logrus.Fields{"order_uid": uid})
+		return err
+	}
+	if err := insertRevision(ctx, tx, order.RevisionDelete, snapshot); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := saveContextOffset(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.LogError("Error committing transaction", err, logrus.Fields{"order_uid": uid})
+		return err
 	}
 
 	logging.LogInfo("Order deleted successfully", logrus.Fields{"order_uid": uid})
 	return nil
 
 }
+
+// RestoreOrder clears the deleted marker. It fails with orders.ErrConflict if
+// the order exists but is not deleted.
+func (r *OrderRepo) RestoreOrder(ctx context.Context, uid string, events ...orders.OutboxEvent) (order.Order, error) {
+	logging.LogInfo("Attempting to restore order", logrus.Fields{"order_uid": uid})
+
+	tx, err := r.repo.Begin(ctx)
+	if err != nil {
+		logging.LogError("Error starting transaction", err, logrus.Fields{"order_uid": uid})
+		return order.Order{}, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	locked, err := lockOrder(ctx, tx, uid)
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFound) {
+			logging.LogError("Order not found to restore", nil, logrus.Fields{"order_uid": uid})
+		}
+		return order.Order{}, err
+	}
+	if locked.DeletedAt == nil {
+		logging.LogError("Order is not deleted", nil, logrus.Fields{"order_uid": uid})
+		return order.Order{}, orders.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, qRestoreOrder, uid); err != nil {
+		logging.LogError("Error executing restore query", err, logrus.Fields{"order_uid": uid})
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return order.Order{}, orders.ErrTimeout
+		}
+		return order.Order{}, err
+	}
+
+	out, err := getOrder(ctx, tx, uid)
+	if err != nil {
+		logging.LogError("Error loading order after restore", err, logrus.Fields{"order_uid": uid})
+		return order.Order{}, err
+	}
+	if err := insertRevision(ctx, tx, order.RevisionRestore, out); err != nil {
+		return order.Order{}, err
+	}
+	if err := insertOutbox(ctx, tx, events); err != nil {
+		return order.Order{}, err
+	}
+	if err := saveContextOffset(ctx, tx); err != nil {
+		return order.Order{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.LogError("Error committing transaction", err, logrus.Fields{"order_uid": uid})
+		return order.Order{}, err
+	}
+
+	logging.LogInfo("Order restored successfully", logrus.Fields{"order_uid": uid})
+	return out, nil
+}
+
+// PurgeDeleted hard-deletes orders soft-deleted before the given time.
+// Their revisions are kept.
+func (r *OrderRepo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.repo.Exec(ctx, qPurgeDeletedOrders, before)
+	if err != nil {
+		logging.LogError("Error purging deleted orders", err, logrus.Fields{"before": before})
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return 0, orders.ErrTimeout
+		}
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	qSaveRate = `
+INSERT INTO exchange_rates (base, quote, valid_from, rate)
+VALUES ($1, $2, $3, $4::numeric)
+ON CONFLICT (base, quote, valid_from) DO UPDATE SET
+  rate      = EXCLUDED.rate,
+  loaded_at = now();`
+
+	// For every (base, at) pair picks the latest rate that starts at or before at.
+	qRatesAt = `
+SELECT q.i, r.rate::text, r.valid_from
+FROM unnest($1::text[], $2::timestamptz[]) WITH ORDINALITY AS q(base, at, i)
+JOIN LATERAL (
+  SELECT rate, valid_from
+  FROM exchange_rates
+  WHERE base = q.base AND quote = $3 AND valid_from <= q.at
+  ORDER BY valid_from DESC
+  LIMIT 1
+) r ON true;`
+)
+
+type ExchangeRateRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewExchangeRateRepo(pool *pgxpool.Pool) *ExchangeRateRepo {
+	return &ExchangeRateRepo{pool: pool}
+}
+
+// SaveRates upserts rates in one transaction; a rate with the same pair and
+// ValidFrom is replaced.
+func (r *ExchangeRateRepo) SaveRates(ctx context.Context, rates []money.Rate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		b := &pgx.Batch{}
+		for _, rt := range rates {
+			b.Queue(qSaveRate, rt.Base, rt.Quote, rt.ValidFrom, rt.Value.FloatString(12))
+		}
+		if err := tx.SendBatch(ctx, b).Close(); err != nil {
+			logging.LogError("Error saving exchange rates", err, logrus.Fields{"count": len(rates)})
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *ExchangeRateRepo) RatesAt(ctx context.Context, quote string, reqs []orders.RateRequest) ([]money.Rate, error) {
+	out := make([]money.Rate, len(reqs))
+	if len(reqs) == 0 {
+		return out, nil
+	}
+	bases := make([]string, len(reqs))
+	ats := make([]time.Time, len(reqs))
+	for i, rq := range reqs {
+		bases[i], ats[i] = rq.Base, rq.At
+		out[i] = money.Rate{Base: rq.Base, Quote: quote}
+	}
+
+	rows, err := r.pool.Query(ctx, qRatesAt, bases, ats, quote)
+	if err != nil {
+		return nil, loadError(ctx, "Error querying exchange rates", err, len(reqs))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			i         int64
+			value     string
+			validFrom time.Time
+		)
+		if err := rows.Scan(&i, &value, &validFrom); err != nil {
+			return nil, loadError(ctx, "Error scanning exchange rate", err, len(reqs))
+		}
+		v, ok := new(big.Rat).SetString(value)
+		if !ok {
+			logging.LogError("Unparsable exchange rate in database", nil, logrus.Fields{"rate": value, "quote": quote})
+			return nil, money.ErrInvalidRate
+		}
+		out[i-1].Value, out[i-1].ValidFrom = v, validFrom
+	}
+	if err := rows.Err(); err != nil {
+		return nil, loadError(ctx, "Error iterating over exchange rates", err, len(reqs))
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// exportBatch is how many orders are fetched from the cursor and loaded at a
+// time; memory use of an export is bounded by it, not by the result size.
+const exportBatch = 500
+
+// ExportOrders streams every order matching f to fn through a server-side
+// cursor opened in a read-only repeatable-read transaction, so the export is
+// a consistent snapshot however long the client takes to read it. It stops at
+// the first error returned by fn or when ctx is cancelled.
+func (r *OrderRepo) ExportOrders(ctx context.Context, f orders.SearchFilters, sortBy, sortDir string, fn func(order.Order) error) error {
+	dir := "DESC"
+	if strings.EqualFold(sortDir, "asc") {
+		dir = "ASC"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+    DECLARE export_orders NO SCROLL CURSOR FOR
+    SELECT o.order_uid
+    FROM orders o
+    LEFT JOIN payments p ON p.order_uid = o.order_uid
+    LEFT JOIN order_search_docs sd ON sd.order_uid = o.order_uid
+    WHERE 1=1
+  `)
+	args := writeSearchFilters(&sb, f, nil)
+	_, col, args := resolveSort(sortBy, f, args)
+	sb.WriteString(" ORDER BY " + col.expr + " " + dir + ", o.order_uid " + dir)
+
+	var total int
+	err := pgx.BeginTxFunc(ctx, r.repo, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sb.String(), args...); err != nil {
+			return loadError(ctx, "Error declaring export cursor", err, 0)
+		}
+		fetch := fmt.Sprintf("FETCH %d FROM export_orders", exportBatch)
+		for {
+			rows, err := tx.Query(ctx, fetch)
+			if err != nil {
+				return loadError(ctx, "Error fetching from export cursor", err, total)
+			}
+			uids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+			if err != nil {
+				return loadError(ctx, "Error scanning export cursor", err, total)
+			}
+
+			list, err := loadOrders(ctx, tx, uids)
+			if err != nil {
+				return err
+			}
+			for _, o := range list {
+				if err := fn(o); err != nil {
+					return err
+				}
+			}
+			total += len(list)
+
+			if len(uids) < exportBatch {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		logging.LogError("Order export aborted", err, logrus.Fields{"exported": total})
+		return err
+	}
+	logging.LogInfo("Orders exported", logrus.Fields{"count": total, "filters": f})
+	return nil
+}
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"errors"
+	"github.com/jackc/pgx/v5"
 	"github.com/reybrally/order-service/internal/app/orders"
 	"github.com/reybrally/order-service/internal/domain/order"
 	"github.com/reybrally/order-service/internal/logging"
@@ -10,7 +11,8 @@ import (
 	"time"
 )
 
-const qFindFullOrderByUID = `
+const (
+	qLoadOrders = `
 SELECT
   o.order_uid,
   o.track_number,
@@ -23,6 +25,9 @@ SELECT
   o.sm_id,
   o.date_created,
   o.oof_shard,
+  o.status,
+  o.version,
+  o.deleted_at,
 
   d.delivery_name,
   d.phone,
@@ -41,155 +46,229 @@ SELECT
   p.bank,
   p.delivery_cost,
   p.goods_total,
-  p.custom_fee,
+  p.custom_fee
+FROM orders o
+LEFT JOIN deliveries   d ON d.order_uid = o.order_uid
+LEFT JOIN payments     p ON p.order_uid = o.order_uid
+WHERE o.order_uid = ANY($1::text[]);
+`
+
+	// Track numbers are not unique; the newest live order wins.
+	qOrderUIDByTrack = `
+SELECT order_uid
+FROM orders
+WHERE track_number = $1 AND deleted_at IS NULL
+ORDER BY date_created DESC, order_uid DESC
+LIMIT 1;
+`
 
+	qLoadItems = `
+SELECT
+  i.order_uid,
   i.chrt_id,
-  i.track_number AS item_track_number,
+  i.track_number,
   i.price,
   i.rid,
-  i.item_name  AS name,
+  i.item_name,
   i.sale,
-  i.item_size  AS size,
+  i.item_size,
   i.total_price,
   i.nm_id,
   i.brand,
   i.status
-FROM orders o
-LEFT JOIN deliveries   d ON d.order_uid = o.order_uid
-LEFT JOIN payments     p ON p.order_uid = o.order_uid
-LEFT JOIN order_items  i ON i.order_uid = o.order_uid
-WHERE o.order_uid = $1;
+FROM order_items i
+WHERE i.order_uid = ANY($1::text[])
+ORDER BY i.order_uid, i.chrt_id;
 `
+)
 
-func (r OrderRepo) GetOrder(ctx context.Context, uid string) (order.Order, error) {
+func (r *OrderRepo) GetOrder(ctx context.Context, uid string) (order.Order, error) {
 	logging.LogInfo("Attempting to fetch order by order_uid", logrus.Fields{"order_uid": uid})
-	r.mu.Lock()
-	defer r.mu.Unlock()
 
-	rows, err := r.repo.Query(ctx, qFindFullOrderByUID, uid)
+	out, err := getOrder(ctx, r.repo, uid)
 	if err != nil {
-		logging.LogError("Error executing query to fetch order", err, logrus.Fields{"order_uid": uid})
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
-			logging.LogError("Context timeout or cancellation while fetching order", err, logrus.Fields{"order_uid": uid})
-			return order.Order{}, orders.ErrTimeout
-		}
 		return order.Order{}, err
 	}
-	defer rows.Close()
-	var (
-		found bool
-		out   order.Order
-	)
+	if out.DeletedAt != nil {
+		logging.LogInfo("Order is soft-deleted", logrus.Fields{"order_uid": uid})
+		return order.Order{}, orders.ErrNotFound
+	}
 
-	for rows.Next() {
-		found = true
+	logging.LogInfo("Order fetched successfully", logrus.Fields{"order_uid": uid})
+	return out, nil
+}
 
-		var (
-			orderUID, trackNumber, entry, locale, internalSig, customerID, deliveryService, shardKey string
-			smID, oofShard                                                                           int64
-			dateCreated                                                                              time.Time
-		)
+// GetOrders returns the live orders among uids in the order of uids; unknown
+// and soft-deleted ids are skipped.
+func (r *OrderRepo) GetOrders(ctx context.Context, uids []string) ([]order.Order, error) {
+	logging.LogInfo("Attempting to fetch orders by order_uid", logrus.Fields{"count": len(uids)})
 
-		var (
-			dName, dPhone, dZip, dCity, dAddress, dRegion, dEmail *string
-		)
+	list, err := loadOrders(ctx, r.repo, uids)
+	if err != nil {
+		return nil, err
+	}
+	out := list[:0]
+	for _, o := range list {
+		if o.DeletedAt == nil {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
 
-		var (
-			pTransaction, pRequestID, pCurrency, pProvider, pBank *string
-			pAmount, pDeliveryCost, pGoodsTotal, pCustomFee       *int64
-			pPaymentDt                                            *time.Time
-		)
+// GetOrderByTrack returns the live order with trackNumber.
+func (r *OrderRepo) GetOrderByTrack(ctx context.Context, trackNumber string) (order.Order, error) {
+	logging.LogInfo("Attempting to fetch order by track_number", logrus.Fields{"track_number": trackNumber})
 
-		var (
-			iChrtID, iItemTrackNumber, iRid, iName, iNmID, iBrand *string
-			iPrice, iSale, iSize, iTotalPrice, iStatus            *int64
-		)
+	var uid string
+	err := r.repo.QueryRow(ctx, qOrderUIDByTrack, trackNumber).Scan(&uid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		logging.LogError("Order not found by track_number", nil, logrus.Fields{"track_number": trackNumber})
+		return order.Order{}, orders.ErrNotFound
+	}
+	if err != nil {
+		return order.Order{}, loadError(ctx, "Error looking up order by track_number", err, 1)
+	}
+	return r.GetOrder(ctx, uid)
+}
 
-		if err := rows.Scan(
-			&orderUID, &trackNumber, &entry, &locale, &internalSig, &customerID, &deliveryService, &shardKey, &smID, &dateCreated, &oofShard,
-			&dName, &dPhone, &dZip, &dCity, &dAddress, &dRegion, &dEmail,
-			&pTransaction, &pRequestID, &pCurrency, &pProvider, &pAmount, &pPaymentDt, &pBank, &pDeliveryCost, &pGoodsTotal, &pCustomFee,
-			&iChrtID, &iItemTrackNumber, &iPrice, &iRid, &iName, &iSale, &iSize, &iTotalPrice, &iNmID, &iBrand, &iStatus,
-		); err != nil {
-			logging.LogError("Error scanning row for order", err, logrus.Fields{"order_uid": uid})
-			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
-				logging.LogError("Context timeout or cancellation while scanning row", err, logrus.Fields{"order_uid": uid})
-				return order.Order{}, orders.ErrTimeout
-			}
-			return order.Order{}, err
-		}
+func getOrder(ctx context.Context, q querier, uid string) (order.Order, error) {
+	list, err := loadOrders(ctx, q, []string{uid})
+	if err != nil {
+		return order.Order{}, err
+	}
+	if len(list) == 0 {
+		logging.LogError("Order not found", nil, logrus.Fields{"order_uid": uid})
+		return order.Order{}, orders.ErrNotFound
+	}
+	return list[0], nil
+}
+
+// loadOrders hydrates the orders with their delivery, payment and items in a
+// single round trip. Soft-deleted orders are included; the result follows the
+// order of uids and skips unknown ids.
+func loadOrders(ctx context.Context, q querier, uids []string) ([]order.Order, error) {
+	if len(uids) == 0 {
+		return []order.Order{}, nil
+	}
 
-		if out.OrderUID == "" {
-			out = order.Order{
-				OrderUID:          orderUID,
-				TrackNumber:       trackNumber,
-				Entry:             entry,
-				Locale:            locale,
-				InternalSignature: internalSig,
-				CustomerId:        customerID,
-				DeliveryService:   deliveryService,
-				ShardKey:          shardKey,
-				SmId:              smID,
-				DateCreated:       dateCreated,
-				OofShard:          oofShard,
-				Delivery: order.Delivery{
-					Name:    derefStr(dName),
-					Phone:   derefStr(dPhone),
-					Zip:     derefStr(dZip),
-					City:    derefStr(dCity),
-					Address: derefStr(dAddress),
-					Region:  derefStr(dRegion),
-					Email:   derefStr(dEmail),
-				},
-				Payment: order.Payment{
-					Transaction:  derefStr(pTransaction),
-					RequestId:    derefStr(pRequestID),
-					Currency:     derefStr(pCurrency),
-					Provider:     derefStr(pProvider),
-					Amount:       derefI64(pAmount),
-					PaymentDt:    derefTime(pPaymentDt),
-					Bank:         derefStr(pBank),
-					DeliveryCost: derefI64(pDeliveryCost),
-					GoodsTotal:   derefI64(pGoodsTotal),
-					CustomFee:    derefI64(pCustomFee),
-				},
-				Items: make([]order.Item, 0, 8),
-			}
+	batch := &pgx.Batch{}
+	batch.Queue(qLoadOrders, uids)
+	batch.Queue(qLoadItems, uids)
+	br := q.SendBatch(ctx, batch)
+	defer br.Close()
+
+	byUID := make(map[string]*order.Order, len(uids))
+
+	rows, err := br.Query()
+	if err != nil {
+		return nil, loadError(ctx, "Error executing query to fetch orders", err, len(uids))
+	}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			rows.Close()
+			return nil, loadError(ctx, "Error scanning order row", err, len(uids))
 		}
+		byUID[o.OrderUID] = &o
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, loadError(ctx, "Error iterating over order rows", err, len(uids))
+	}
 
-		if iChrtID != nil {
-			out.Items = append(out.Items, order.Item{
-				ChrtId:      derefStr(iChrtID),
-				TrackNumber: derefStr(iItemTrackNumber),
-				Price:       derefI64(iPrice),
-				Rid:         derefStr(iRid),
-				Name:        derefStr(iName),
-				Sale:        derefI64(iSale),
-				Size:        derefI64(iSize),
-				TotalPrice:  derefI64(iTotalPrice),
-				NmId:        derefStr(iNmID),
-				Brand:       derefStr(iBrand),
-				Status:      derefI64(iStatus),
-			})
+	rows, err = br.Query()
+	if err != nil {
+		return nil, loadError(ctx, "Error executing query to fetch order items", err, len(uids))
+	}
+	for rows.Next() {
+		var (
+			uid string
+			it  order.Item
+		)
+		if err := rows.Scan(&uid, &it.ChrtId, &it.TrackNumber, &it.Price, &it.Rid, &it.Name,
+			&it.Sale, &it.Size, &it.TotalPrice, &it.NmId, &it.Brand, &it.Status); err != nil {
+			rows.Close()
+			return nil, loadError(ctx, "Error scanning order item row", err, len(uids))
+		}
+		if o, ok := byUID[uid]; ok {
+			o.Items = append(o.Items, it)
 		}
 	}
+	rows.Close()
 	if err := rows.Err(); err != nil {
-		logging.LogError("Error iterating over rows", err, logrus.Fields{"order_uid": uid})
-		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
-			logging.LogError("Context timeout or cancellation while iterating rows", err, logrus.Fields{"order_uid": uid})
-			return order.Order{}, orders.ErrTimeout
+		return nil, loadError(ctx, "Error iterating over order item rows", err, len(uids))
+	}
+
+	out := make([]order.Order, 0, len(byUID))
+	for _, uid := range uids {
+		if o, ok := byUID[uid]; ok {
+			out = append(out, *o)
+			delete(byUID, uid)
 		}
-		return order.Order{}, err
 	}
-	if !found {
-		logging.LogError("Order not found", nil, logrus.Fields{"order_uid": uid})
-		return order.Order{}, orders.ErrNotFound
+	return out, nil
+}
+
+func scanOrder(rows pgx.Rows) (order.Order, error) {
+	var (
+		dName, dPhone, dZip, dCity, dAddress, dRegion, dEmail *string
+	)
+
+	var (
+		pTransaction, pRequestID, pCurrency, pProvider, pBank *string
+		pAmount, pDeliveryCost, pGoodsTotal, pCustomFee       *int64
+		pPaymentDt                                            *time.Time
+	)
+
+	var (
+		out    order.Order
+		status string
+	)
+	if err := rows.Scan(
+		&out.OrderUID, &out.TrackNumber, &out.Entry, &out.Locale, &out.InternalSignature, &out.CustomerId,
+		&out.DeliveryService, &out.ShardKey, &out.SmId, &out.DateCreated, &out.OofShard, &status, &out.Version, &out.DeletedAt,
+		&dName, &dPhone, &dZip, &dCity, &dAddress, &dRegion, &dEmail,
+		&pTransaction, &pRequestID, &pCurrency, &pProvider, &pAmount, &pPaymentDt, &pBank, &pDeliveryCost, &pGoodsTotal, &pCustomFee,
+	); err != nil {
+		return order.Order{}, err
 	}
 
-	logging.LogInfo("Order fetched successfully", logrus.Fields{"order_uid": uid})
+	out.Status = order.Status(status)
+	out.Delivery = order.Delivery{
+		Name:    derefStr(dName),
+		Phone:   derefStr(dPhone),
+		Zip:     derefStr(dZip),
+		City:    derefStr(dCity),
+		Address: derefStr(dAddress),
+		Region:  derefStr(dRegion),
+		Email:   derefStr(dEmail),
+	}
+	out.Payment = order.Payment{
+		Transaction:  derefStr(pTransaction),
+		RequestId:    derefStr(pRequestID),
+		Currency:     derefStr(pCurrency),
+		Provider:     derefStr(pProvider),
+		Amount:       derefI64(pAmount),
+		PaymentDt:    derefTime(pPaymentDt),
+		Bank:         derefStr(pBank),
+		DeliveryCost: derefI64(pDeliveryCost),
+		GoodsTotal:   derefI64(pGoodsTotal),
+		CustomFee:    derefI64(pCustomFee),
+	}
+	out.Items = make([]order.Item, 0, 8)
 	return out, nil
 }
 
+func loadError(ctx context.Context, msg string, err error, count int) error {
+	logging.LogError(msg, err, logrus.Fields{"count": count})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		logging.LogError("Context timeout or cancellation while fetching orders", err, logrus.Fields{"count": count})
+		return orders.ErrTimeout
+	}
+	return err
+}
+
 func derefStr(p *string) string {
 	if p == nil {
 		return ""
@@ -0,0 +1,132 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	// An in-flight key holds a short lock and a completed one its TTL; once
+	// either has passed, the key is taken over as if it were new.
+	qReserveIdempotencyKey = `
+INSERT INTO idempotency_keys (key, fingerprint, created_at, expires_at)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT (key) DO UPDATE SET
+  fingerprint = EXCLUDED.fingerprint,
+  status_code = NULL,
+  headers     = NULL,
+  body        = NULL,
+  created_at  = EXCLUDED.created_at,
+  expires_at  = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+RETURNING key;`
+
+	qGetIdempotencyKey = `
+SELECT fingerprint, status_code, headers, body
+FROM idempotency_keys
+WHERE key = $1 AND expires_at > $2;`
+
+	qCompleteIdempotencyKey = `
+UPDATE idempotency_keys SET status_code = $2, headers = $3, body = $4, expires_at = $5
+WHERE key = $1 AND status_code IS NULL;`
+
+	qReleaseIdempotencyKey = `
+DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL;`
+
+	qPurgeIdempotencyKeys = `
+DELETE FROM idempotency_keys WHERE expires_at <= $1;`
+)
+
+type IdempotencyRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdempotencyRepo(pool *pgxpool.Pool) *IdempotencyRepo {
+	return &IdempotencyRepo{pool: pool}
+}
+
+func (r *IdempotencyRepo) Reserve(ctx context.Context, key, fingerprint string, lock time.Duration) (orders.IdempotencyRecord, bool, error) {
+	rec := orders.IdempotencyRecord{Key: key, Fingerprint: fingerprint}
+	// The existing key may expire or be released between the two statements;
+	// the second attempt then reserves it.
+	for attempt := 0; attempt < 2; attempt++ {
+		now := time.Now().UTC()
+		err := r.pool.QueryRow(ctx, qReserveIdempotencyKey, key, fingerprint, now, now.Add(lock)).Scan(&rec.Key)
+		if err == nil {
+			return rec, true, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logging.LogError("Error reserving idempotency key", err, logrus.Fields{"key": key})
+			return rec, false, err
+		}
+
+		var (
+			status  *int
+			headers []byte
+			body    []byte
+		)
+		err = r.pool.QueryRow(ctx, qGetIdempotencyKey, key, now).Scan(&rec.Fingerprint, &status, &headers, &body)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			logging.LogError("Error reading idempotency key", err, logrus.Fields{"key": key})
+			return rec, false, err
+		}
+		if status != nil {
+			resp := &orders.IdempotentResponse{Status: *status, Body: body}
+			if len(headers) > 0 {
+				if err := json.Unmarshal(headers, &resp.Header); err != nil {
+					logging.LogError("Error decoding stored response headers", err, logrus.Fields{"key": key})
+					return rec, false, err
+				}
+			}
+			rec.Response = resp
+		}
+		return rec, false, nil
+	}
+	logging.LogError("Idempotency key could not be reserved", nil, logrus.Fields{"key": key})
+	return rec, false, orders.ErrRetryable
+}
+
+func (r *IdempotencyRepo) Complete(ctx context.Context, key string, resp orders.IdempotentResponse, ttl time.Duration) error {
+	headers := resp.Header
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	hdrs, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	if _, err := r.pool.Exec(ctx, qCompleteIdempotencyKey, key, resp.Status, hdrs, resp.Body, time.Now().UTC().Add(ttl)); err != nil {
+		logging.LogError("Error storing idempotent response", err, logrus.Fields{"key": key, "status": resp.Status})
+		return err
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) Release(ctx context.Context, key string) error {
+	if _, err := r.pool.Exec(ctx, qReleaseIdempotencyKey, key); err != nil {
+		logging.LogError("Error releasing idempotency key", err, logrus.Fields{"key": key})
+		return err
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) PurgeExpired(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, qPurgeIdempotencyKeys, time.Now().UTC())
+	if err != nil {
+		logging.LogError("Error purging expired idempotency keys", err, logrus.Fields{})
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
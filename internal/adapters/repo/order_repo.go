@@ -1,9 +1,11 @@
 package repo
 
 import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/reybrally/order-service/internal/domain/order"
-	"sync"
 	"time"
 )
 
@@ -32,9 +34,19 @@ type ItemRow struct {
 	Status      int64
 }
 
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// OrderRepo is safe for concurrent use. Writers of the same order are
+// serialized by the row lock on orders taken inside each transaction, so
+// different orders are written in parallel up to the pool size.
 type OrderRepo struct {
 	repo *pgxpool.Pool
-	mu   sync.Mutex
 }
 
 type PaymentRow struct {
@@ -57,6 +69,8 @@ type OrderRow struct {
 	SmId              int64
 	DateCreated       time.Time
 	OofShard          int64
+	Status            string
+	Version           int64
 }
 
 func (o *OrderRow) ToDomain() order.Order {
@@ -64,5 +78,6 @@ func (o *OrderRow) ToDomain() order.Order {
 		Entry: o.Entry, Locale: o.Locale, InternalSignature: o.InternalSignature,
 		CustomerId: o.CustomerId, DeliveryService: o.DeliveryService, ShardKey: o.ShardKey,
 		SmId: o.SmId, DateCreated: o.DateCreated, OofShard: o.OofShard,
+		Status: order.Status(o.Status), Version: o.Version,
 	}
 }
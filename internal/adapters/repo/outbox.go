@@ -0,0 +1,197 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	qInsertOutbox = `
+INSERT INTO outbox (aggregate_id, topic, event_type, msg_key, payload, headers)
+VALUES ($1,$2,$3,$4,$5,$6);`
+
+	// Only the oldest unsent event of every aggregate is picked up, so events
+	// of one order are never published out of order, even by several relays.
+	// A dead-lettered ('failed') event keeps blocking the later events of its
+	// order until it is requeued or deleted by hand. Claimed events are leased
+	// by moving next_attempt_at to $2: they stay pending, so they keep blocking
+	// their successors, and are picked up again if the relay dies.
+	qClaimOutbox = `
+UPDATE outbox o SET next_attempt_at = $2
+WHERE o.id IN (
+  SELECT c.id
+  FROM outbox c
+  WHERE c.status = 'pending'
+    AND c.next_attempt_at <= now()
+    AND NOT EXISTS (
+      SELECT 1 FROM outbox prev
+      WHERE prev.aggregate_id = c.aggregate_id
+        AND prev.status IN ('pending', 'failed')
+        AND prev.id < c.id
+    )
+  ORDER BY c.id
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING o.id, o.aggregate_id, o.topic, o.event_type, o.msg_key, o.payload, o.headers, o.attempts, o.created_at;`
+
+	qMarkOutboxSent = `
+UPDATE outbox SET status = 'sent', attempts = attempts + 1, sent_at = now(), last_error = NULL
+WHERE id = $1;`
+
+	qMarkOutboxFailed = `
+UPDATE outbox SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5
+WHERE id = $1;`
+
+	qPurgeOutboxSent = `DELETE FROM outbox WHERE status = 'sent' AND sent_at < $1;`
+
+	maxOutboxBackoff = 5 * time.Minute
+	// defaultOutboxLease is how long claimed events are reserved for the relay
+	// that claimed them when OutboxDispatchOptions.Lease is not set.
+	defaultOutboxLease = 30 * time.Second
+)
+
+type OutboxRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepo(pool *pgxpool.Pool) *OutboxRepo { return &OutboxRepo{pool: pool} }
+
+func insertOutbox(ctx context.Context, tx pgx.Tx, events []orders.OutboxEvent) error {
+	for _, ev := range events {
+		args, err := outboxArgs(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, qInsertOutbox, args...); err != nil {
+			logging.LogError("Error inserting outbox event", err, logrus.Fields{
+				"aggregate_id": ev.AggregateID, "event_type": ev.EventType,
+			})
+			return err
+		}
+	}
+	return nil
+}
+
+// outboxArgs returns the arguments of qInsertOutbox for ev.
+func outboxArgs(ev orders.OutboxEvent) ([]any, error) {
+	headers := ev.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	hdrs, err := json.Marshal(headers)
+	if err != nil {
+		return nil, err
+	}
+	return []any{ev.AggregateID, ev.Topic, ev.EventType, ev.Key, ev.Payload, hdrs}, nil
+}
+
+// DispatchPending claims up to opts.Limit events and publishes them with send.
+// The claim is committed before publishing, so no transaction or row lock is
+// held while the broker is slow; events whose send could not finish within
+// opts.Lease are left to be claimed again.
+func (r *OutboxRepo) DispatchPending(ctx context.Context, opts orders.OutboxDispatchOptions, send orders.OutboxSender) (orders.OutboxDispatchResult, error) {
+	var res orders.OutboxDispatchResult
+	if opts.Limit <= 0 {
+		opts.Limit = 100
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaultOutboxLease
+	}
+
+	leaseEnd := time.Now().UTC().Add(opts.Lease)
+	rows, err := r.pool.Query(ctx, qClaimOutbox, opts.Limit, leaseEnd)
+	if err != nil {
+		logging.LogError("Error claiming outbox events", err, logrus.Fields{})
+		return res, err
+	}
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (orders.OutboxRecord, error) {
+		var (
+			rec  orders.OutboxRecord
+			hdrs []byte
+		)
+		if err := row.Scan(&rec.ID, &rec.AggregateID, &rec.Topic, &rec.EventType, &rec.Key,
+			&rec.Payload, &hdrs, &rec.Attempts, &rec.CreatedAt); err != nil {
+			return rec, err
+		}
+		if len(hdrs) > 0 {
+			if err := json.Unmarshal(hdrs, &rec.Headers); err != nil {
+				return rec, err
+			}
+		}
+		return rec, nil
+	})
+	if err != nil {
+		logging.LogError("Error scanning outbox events", err, logrus.Fields{})
+		return res, err
+	}
+	// RETURNING does not keep the order of the subquery.
+	slices.SortFunc(records, func(a, b orders.OutboxRecord) int { return cmp.Compare(a.ID, b.ID) })
+
+	for _, rec := range records {
+		if time.Now().UTC().Add(opts.SendTimeout).After(leaseEnd) {
+			logging.LogWarn("Outbox lease expired, leaving events for the next claim", logrus.Fields{"outbox_id": rec.ID})
+			break
+		}
+		if sendErr := send(ctx, rec); sendErr != nil {
+			attempts := rec.Attempts + 1
+			status := orders.OutboxStatusPending
+			if opts.MaxAttempts > 0 && attempts >= opts.MaxAttempts {
+				status = orders.OutboxStatusFailed
+				res.Dead++
+			} else {
+				res.Failed++
+			}
+			next := time.Now().UTC().Add(outboxBackoff(opts.Backoff, attempts))
+			if _, err := r.pool.Exec(ctx, qMarkOutboxFailed, rec.ID, status, attempts, sendErr.Error(), next); err != nil {
+				logging.LogError("Error marking outbox event as failed", err, logrus.Fields{"outbox_id": rec.ID})
+				return res, err
+			}
+			logging.LogError("Outbox event publish failed", sendErr, logrus.Fields{
+				"outbox_id": rec.ID, "aggregate_id": rec.AggregateID, "event_type": rec.EventType,
+				"attempts": attempts, "status": status,
+			})
+			continue
+		}
+		if _, err := r.pool.Exec(ctx, qMarkOutboxSent, rec.ID); err != nil {
+			// The event was published; it is published again after the lease.
+			logging.LogError("Error marking outbox event as sent", err, logrus.Fields{"outbox_id": rec.ID})
+			return res, err
+		}
+		res.Sent++
+	}
+	return res, nil
+}
+
+func (r *OutboxRepo) PurgeSent(ctx context.Context, before time.Time) (int64, error) {
+	ct, err := r.pool.Exec(ctx, qPurgeOutboxSent, before)
+	if err != nil {
+		logging.LogError("Error purging sent outbox events", err, logrus.Fields{"before": before})
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+func outboxBackoff(base time.Duration, attempts int) time.Duration {
+	if base <= 0 {
+		base = time.Second
+	}
+	d := base
+	for i := 1; i < attempts && d < maxOutboxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxOutboxBackoff {
+		d = maxOutboxBackoff
+	}
+	return d
+}
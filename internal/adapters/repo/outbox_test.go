@@ -0,0 +1,15 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, outboxBackoff(0, 1))
+	assert.Equal(t, 2*time.Second, outboxBackoff(2*time.Second, 1))
+	assert.Equal(t, 8*time.Second, outboxBackoff(2*time.Second, 3))
+	assert.Equal(t, maxOutboxBackoff, outboxBackoff(time.Second, 30))
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	qLockOrder = `SELECT version, status, deleted_at FROM orders WHERE order_uid = $1 FOR UPDATE;`
+
+	qInsertRevision = `
+INSERT INTO order_revisions (order_uid, rev, op, snapshot, actor, request_id)
+SELECT $1::text, COALESCE(MAX(rev), 0) + 1, $2, $3, $4, $5
+FROM order_revisions
+WHERE order_uid = $1::text;`
+
+	qListRevisions = `
+SELECT order_uid, rev, op, actor, request_id, created_at
+FROM order_revisions
+WHERE order_uid = $1
+ORDER BY rev;`
+
+	qGetRevision = `
+SELECT order_uid, rev, op, actor, request_id, created_at, snapshot
+FROM order_revisions
+WHERE order_uid = $1 AND rev = $2;`
+)
+
+type lockedOrder struct {
+	Version   int64
+	Status    order.Status
+	DeletedAt *time.Time
+}
+
+// lockOrder takes a row lock on the order for the rest of tx, so concurrent
+// writers of the same order queue up behind it. Soft-deleted orders are
+// locked and returned too.
+func lockOrder(ctx context.Context, tx pgx.Tx, uid string) (lockedOrder, error) {
+	var (
+		out    lockedOrder
+		status string
+	)
+	if err := tx.QueryRow(ctx, qLockOrder, uid).Scan(&out.Version, &status, &out.DeletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return lockedOrder{}, orders.ErrNotFound
+		}
+		logging.LogError("Error locking order row", err, logrus.Fields{"order_uid": uid})
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return lockedOrder{}, orders.ErrTimeout
+		}
+		return lockedOrder{}, err
+	}
+	out.Status = order.Status(status)
+	return out, nil
+}
+
+func insertRevision(ctx context.Context, tx pgx.Tx, op order.RevisionOp, snapshot order.Order) error {
+	args, err := revisionArgs(ctx, op, snapshot)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, qInsertRevision, args...); err != nil {
+		logging.LogError("Error inserting order revision", err, logrus.Fields{"order_uid": snapshot.OrderUID, "op": op})
+		return err
+	}
+	return nil
+}
+
+// revisionArgs returns the arguments of qInsertRevision; the actor comes from
+// ctx.
+func revisionArgs(ctx context.Context, op order.RevisionOp, snapshot order.Order) ([]any, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	actor := orders.ActorFromContext(ctx)
+	return []any{snapshot.OrderUID, string(op), data, actor.Name, actor.RequestID}, nil
+}
+
+func (r *OrderRepo) Revisions(ctx context.Context, uid string) ([]order.Revision, error) {
+	rows, err := r.repo.Query(ctx, qListRevisions, uid)
+	if err != nil {
+		logging.LogError("Error fetching order revisions", err, logrus.Fields{"order_uid": uid})
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]order.Revision, 0, 8)
+	for rows.Next() {
+		var (
+			rev order.Revision
+			op  string
+		)
+		if err := rows.Scan(&rev.OrderUID, &rev.Rev, &op, &rev.Actor, &rev.RequestID, &rev.CreatedAt); err != nil {
+			logging.LogError("Error scanning order revision", err, logrus.Fields{"order_uid": uid})
+			return nil, err
+		}
+		rev.Op = order.RevisionOp(op)
+		out = append(out, rev)
+	}
+	if err := rows.Err(); err != nil {
+		logging.LogError("Error iterating order revisions", err, logrus.Fields{"order_uid": uid})
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *OrderRepo) Revision(ctx context.Context, uid string, rev int64) (order.Revision, error) {
+	var (
+		out  order.Revision
+		op   string
+		data []byte
+	)
+	err := r.repo.QueryRow(ctx, qGetRevision, uid, rev).Scan(
+		&out.OrderUID, &out.Rev, &op, &out.Actor, &out.RequestID, &out.CreatedAt, &data,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return order.Revision{}, orders.ErrNotFound
+	}
+	if err != nil {
+		logging.LogError("Error fetching order revision", err, logrus.Fields{"order_uid": uid, "rev": rev})
+		return order.Revision{}, err
+	}
+	var snap order.Order
+	if err := json.Unmarshal(data, &snap); err != nil {
+		logging.LogError("Error decoding order revision snapshot", err, logrus.Fields{"order_uid": uid, "rev": rev})
+		return order.Revision{}, err
+	}
+	out.Op = order.RevisionOp(op)
+	out.Snapshot = &snap
+	return out, nil
+}
@@ -4,38 +4,171 @@ import (
 	"context"
 	"fmt"
 	"github.com/reybrally/order-service/internal/app/orders"
-	"github.com/reybrally/order-service/internal/domain/order"
 	"github.com/reybrally/order-service/internal/logging"
 	"github.com/sirupsen/logrus"
 	"strings"
 )
 
-var sortWhitelist = map[string]string{
-	"date_created": "o.date_created",
-	"customer_id":  "o.customer_id",
-	"track_number": "o.track_number",
-	"amount":       "p.amount",
+var sortWhitelist = map[string]sortColumn{
+	"date_created": {expr: "o.date_created", kind: sortTime},
+	"customer_id":  {expr: "o.customer_id", kind: sortText},
+	"track_number": {expr: "o.track_number", kind: sortText},
+	"amount":       {expr: "COALESCE(p.amount, 0)", kind: sortInt},
 }
 
-func (r *OrderRepo) SearchOrders(ctx context.Context, f orders.SearchFilters, p orders.PageRequest) ([]order.Order, error) {
+// sortRelevance orders by how well an order matches the q filter. It needs q;
+// the handlers reject it without one.
+const sortRelevance = "relevance"
+
+// relevanceExpr scores the search document against the query in $n: the
+// full-text rank plus the trigram similarity of the closest run of whole words.
+func relevanceExpr(n int) string {
+	return fmt.Sprintf(`(ts_rank_cd(sd.document, websearch_to_tsquery('simple', $%[1]d))
+      + strict_word_similarity($%[1]d, sd.haystack))::float8`, n)
+}
+
+// resolveSort maps sortBy to the column to order by. relevance scores against
+// f.Query and appends it to args; an unknown column, or relevance without q,
+// falls back to date_created.
+func resolveSort(sortBy string, f orders.SearchFilters, args []any) (string, sortColumn, []any) {
+	if sortBy == sortRelevance && f.Query != nil {
+		args = append(args, *f.Query)
+		return sortBy, sortColumn{expr: relevanceExpr(len(args)), kind: sortFloat}, args
+	}
+	if col, ok := sortWhitelist[sortBy]; ok {
+		return sortBy, col, args
+	}
+	return "date_created", sortWhitelist["date_created"], args
+}
+
+func (r *OrderRepo) SearchOrders(ctx context.Context, f orders.SearchFilters, p orders.PageRequest) (orders.Page, error) {
 	logging.LogInfo("Starting order search", logrus.Fields{
 		"filters":      f,
 		"page_request": p,
 	})
 
-	var (
-		sb   strings.Builder
-		args []any
-		n    = 1
-	)
+	var where strings.Builder
+	args := writeSearchFilters(&where, f, nil)
 
+	sortBy, col, args := resolveSort(p.SortBy, f, args)
+	dir := strings.ToLower(p.SortDir)
+	if dir != "asc" && dir != "desc" {
+		dir = "desc"
+	}
+
+	var cursor *searchCursor
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor, sortBy, dir)
+		if err != nil {
+			logging.LogError("Invalid search cursor", err, logrus.Fields{"sort_by": sortBy, "sort_dir": dir})
+			return orders.Page{}, err
+		}
+		cursor = &c
+	}
+
+	var sb strings.Builder
 	sb.WriteString(`
-    SELECT o.order_uid
+    SELECT o.order_uid, ` + col.expr + `
     FROM orders o
     LEFT JOIN payments p ON p.order_uid = o.order_uid
+    LEFT JOIN order_search_docs sd ON sd.order_uid = o.order_uid
     WHERE 1=1
   `)
+	sb.WriteString(where.String())
+	n := len(args) + 1
+
+	if cursor != nil {
+		v, err := parseSortValue(col.kind, cursor.Value)
+		if err != nil {
+			return orders.Page{}, err
+		}
+		cmp := "<"
+		if dir == "asc" {
+			cmp = ">"
+		}
+		sb.WriteString(fmt.Sprintf(" AND (%s, o.order_uid) %s ($%d, $%d)", col.expr, cmp, n, n+1))
+		args = append(args, v, cursor.UID)
+		n += 2
+	}
+
+	sqlDir := strings.ToUpper(dir)
+	sb.WriteString(" ORDER BY " + col.expr + " " + sqlDir + ", o.order_uid " + sqlDir)
+
+	if p.Limit <= 0 || p.Limit > 100 {
+		p.Limit = 20
+	}
+	// One extra row tells whether there is a next page.
+	sb.WriteString(fmt.Sprintf(" LIMIT $%d", n))
+	args = append(args, p.Limit+1)
+	n++
+	if p.Offset > 0 && cursor == nil {
+		sb.WriteString(fmt.Sprintf(" OFFSET $%d", n))
+		args = append(args, p.Offset)
+		n++
+	}
+
+	rows, err := r.repo.Query(ctx, sb.String(), args...)
+	if err != nil {
+		logging.LogError("Error executing search query", err, logrus.Fields{"query": sb.String(), "args": args})
+		return orders.Page{}, err
+	}
+	defer rows.Close()
+
+	var (
+		uids []string
+		keys []any
+	)
+	for rows.Next() {
+		var (
+			id  string
+			key any
+		)
+		if err := rows.Scan(&id, &key); err != nil {
+			logging.LogError("Error scanning row in search query", err, logrus.Fields{"query": sb.String(), "args": args})
+			return orders.Page{}, err
+		}
+		uids = append(uids, id)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		logging.LogError("Error iterating over rows", err, logrus.Fields{"query": sb.String(), "args": args})
+		return orders.Page{}, err
+	}
+
+	var page orders.Page
+	if len(uids) > p.Limit {
+		uids, keys = uids[:p.Limit], keys[:p.Limit]
+		last := len(uids) - 1
+		page.NextCursor = encodeCursor(searchCursor{
+			SortBy:  sortBy,
+			SortDir: dir,
+			Value:   formatSortValue(col.kind, keys[last]),
+			UID:     uids[last],
+		})
+	}
+
+	logging.LogInfo("Found order_uids", logrus.Fields{"order_uids": uids})
+
+	list, err := loadOrders(ctx, r.repo, uids)
+	if err != nil {
+		logging.LogError("Error loading orders for search page", err, logrus.Fields{"order_uids": uids})
+		return orders.Page{}, err
+	}
+	page.Orders = list
+	logging.LogInfo("Search completed successfully", logrus.Fields{
+		"found_orders": len(page.Orders),
+	})
+	return page, nil
+}
 
+// writeSearchFilters appends the WHERE conditions of f to sb, numbering
+// placeholders after args, and returns args with the filter values appended.
+// The query must alias orders as o, payments as p and order_search_docs as sd.
+func writeSearchFilters(sb *strings.Builder, f orders.SearchFilters, args []any) []any {
+	n := len(args) + 1
+	if !f.IncludeDeleted {
+		sb.WriteString(" AND o.deleted_at IS NULL")
+	}
 	if f.CreatedFrom != nil {
 		sb.WriteString(fmt.Sprintf(" AND o.date_created >= $%d", n))
 		args = append(args, *f.CreatedFrom)
@@ -72,73 +205,69 @@ func (r *OrderRepo) SearchOrders(ctx context.Context, f orders.SearchFilters, p
 		n++
 	}
 	if f.Query != nil {
+		// Words match the full-text document, typos and fragments of words
+		// the trigram index; ILIKE keeps substrings of identifiers working.
 		sb.WriteString(fmt.Sprintf(`
       AND (
-        o.order_uid    ILIKE $%[1]d OR
-        o.track_number ILIKE $%[1]d OR
-        o.customer_id  ILIKE $%[1]d OR
-        p.transaction  ILIKE $%[1]d
-      )`, n))
-		args = append(args, "%"+*f.Query+"%")
-		n++
-	}
-
-	col, ok := sortWhitelist[p.SortBy]
-	if !ok {
-		col = "o.date_created"
-	}
-	dir := strings.ToUpper(p.SortDir)
-	if dir != "ASC" && dir != "DESC" {
-		dir = "DESC"
+        sd.document @@ websearch_to_tsquery('simple', $%[1]d) OR
+        $%[1]d <<%% sd.haystack OR
+        sd.haystack ILIKE $%[2]d
+      )`, n, n+1))
+		args = append(args, *f.Query, "%"+*f.Query+"%")
+		n += 2
 	}
-	sb.WriteString(" ORDER BY " + col + " " + dir)
-
-	if p.Limit <= 0 || p.Limit > 100 {
-		p.Limit = 20
+	if len(f.DeliveryServices) > 0 {
+		sb.WriteString(fmt.Sprintf(" AND o.delivery_service = ANY($%d)", n))
+		args = append(args, f.DeliveryServices)
+		n++
 	}
-	sb.WriteString(fmt.Sprintf(" LIMIT $%d", n))
-	args = append(args, p.Limit)
-	n++
-	if p.Offset > 0 {
-		sb.WriteString(fmt.Sprintf(" OFFSET $%d", n))
-		args = append(args, p.Offset)
+	if f.AmountMin != nil {
+		sb.WriteString(fmt.Sprintf(" AND p.amount >= $%d", n))
+		args = append(args, *f.AmountMin)
 		n++
 	}
-
-	rows, err := r.repo.Query(ctx, sb.String(), args...)
-	if err != nil {
-		logging.LogError("Error executing search query", err, logrus.Fields{"query": sb.String(), "args": args})
-		return nil, err
+	if f.AmountMax != nil {
+		sb.WriteString(fmt.Sprintf(" AND p.amount <= $%d", n))
+		args = append(args, *f.AmountMax)
+		n++
 	}
-	defer rows.Close()
 
-	var uids []string
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			logging.LogError("Error scanning row in search query", err, logrus.Fields{"query": sb.String(), "args": args})
-			return nil, err
-		}
-		uids = append(uids, id)
-	}
-	if err := rows.Err(); err != nil {
-		logging.LogError("Error iterating over rows", err, logrus.Fields{"query": sb.String(), "args": args})
-		return nil, err
-	}
+	// Brand, city, region and email are compared case-insensitively; the
+	// handler lower-cases the values.
+	args = writeExists(sb, "order_items i", "i.order_uid", []listFilter{
+		{"i.nm_id", f.NmIDs},
+		{"lower(i.brand)", f.Brands},
+		{"i.chrt_id", f.ChrtIDs},
+		{"i.rid", f.Rids},
+	}, args)
+	args = writeExists(sb, "deliveries d", "d.order_uid", []listFilter{
+		{"lower(d.city)", f.Cities},
+		{"lower(d.region)", f.Regions},
+		{"d.phone", f.Phones},
+		{"lower(d.email)", f.Emails},
+	}, args)
+	return args
+}
 
-	logging.LogInfo("Found order_uids", logrus.Fields{"order_uids": uids})
+type listFilter struct {
+	expr   string
+	values []string
+}
 
-	out := make([]order.Order, 0, len(uids))
-	for _, id := range uids {
-		o, err := r.GetOrder(ctx, id)
-		if err != nil {
-			logging.LogError("Error fetching order by order_uid", err, logrus.Fields{"order_uid": id})
-			return nil, err
+// writeExists adds an EXISTS requiring one row of table, linked to the order
+// by its uid column, to match every non-empty filter.
+func writeExists(sb *strings.Builder, table, uid string, filters []listFilter, args []any) []any {
+	var conds []string
+	for _, lf := range filters {
+		if len(lf.values) == 0 {
+			continue
 		}
-		out = append(out, o)
+		args = append(args, lf.values)
+		conds = append(conds, fmt.Sprintf("%s = ANY($%d)", lf.expr, len(args)))
 	}
-	logging.LogInfo("Search completed successfully", logrus.Fields{
-		"found_orders": len(out),
-	})
-	return out, nil
+	if len(conds) == 0 {
+		return args
+	}
+	sb.WriteString(" AND EXISTS (SELECT 1 FROM " + table + " WHERE " + uid + " = o.order_uid AND " + strings.Join(conds, " AND ") + ")")
+	return args
 }
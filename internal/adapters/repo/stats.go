@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+type statsDimension struct {
+	key  string
+	join string
+}
+
+// Every dimension but brand aggregates whole orders; brand aggregates items,
+// so an order with several brands counts once in each of them.
+var statsDimensions = map[string]statsDimension{
+	orders.StatsByDay:             {key: "to_char(o.date_created AT TIME ZONE 'UTC', 'YYYY-MM-DD')"},
+	orders.StatsByProvider:        {key: "COALESCE(p.provider, '')"},
+	orders.StatsByCurrency:        {key: "COALESCE(p.currency, '')"},
+	orders.StatsByDeliveryService: {key: "o.delivery_service"},
+	orders.StatsByRegion:          {key: "COALESCE(d.region, '')", join: "LEFT JOIN deliveries d ON d.order_uid = o.order_uid"},
+	orders.StatsByBrand:           {key: "i.brand", join: "JOIN order_items i ON i.order_uid = o.order_uid"},
+}
+
+// OrderStats aggregates the orders matching f by req.GroupBy and payment
+// currency. With a reporting currency every row also carries the sum of
+// amount*rate, using the rate in effect at payment_dt.
+func (r *OrderRepo) OrderStats(ctx context.Context, f orders.SearchFilters, req orders.StatsRequest) ([]orders.StatsRow, error) {
+	dim, ok := statsDimensions[req.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown stats dimension %q", orders.ErrInvalidData, req.GroupBy)
+	}
+
+	amount, items := "p.amount", "(SELECT count(*) FROM order_items ic WHERE ic.order_uid = o.order_uid)"
+	if req.GroupBy == orders.StatsByBrand {
+		amount, items = "i.total_price", "1"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(`
+WITH facts AS (
+  SELECT ` + dim.key + ` AS key, p.currency, p.payment_dt, o.order_uid,
+         ` + amount + ` AS amount, ` + items + ` AS items
+  FROM orders o
+  LEFT JOIN payments p ON p.order_uid = o.order_uid
+  LEFT JOIN order_search_docs sd ON sd.order_uid = o.order_uid
+  ` + dim.join + `
+  WHERE 1=1`)
+	args := writeSearchFilters(&sb, f, nil)
+	sb.WriteString(`
+)`)
+
+	rated, missing, rateJoin := "NULL::text", "0::bigint", ""
+	if req.ReportingCurrency != "" {
+		args = append(args, req.ReportingCurrency)
+		q := fmt.Sprintf("$%d", len(args))
+		rated = "SUM(f.amount * r.rate)::text"
+		missing = "COUNT(DISTINCT f.order_uid) FILTER (WHERE f.currency IS NOT NULL AND r.rate IS NULL)"
+		rateJoin = `
+LEFT JOIN LATERAL (
+  SELECT CASE WHEN f.currency = ` + q + ` THEN 1::numeric ELSE (
+    SELECT er.rate FROM exchange_rates er
+    WHERE er.base = f.currency AND er.quote = ` + q + ` AND er.valid_from <= f.payment_dt
+    ORDER BY er.valid_from DESC
+    LIMIT 1
+  ) END AS rate
+) r ON true`
+	}
+
+	sb.WriteString(`
+SELECT f.key, COALESCE(f.currency, ''), COUNT(DISTINCT f.order_uid), COALESCE(SUM(f.items), 0)::bigint,
+       COALESCE(SUM(f.amount), 0)::bigint, ` + rated + `, ` + missing + `
+FROM facts f` + rateJoin + `
+GROUP BY f.key, f.currency
+ORDER BY f.key, f.currency`)
+
+	rows, err := r.repo.Query(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, loadError(ctx, "Error executing stats query", err, 0)
+	}
+	defer rows.Close()
+
+	var out []orders.StatsRow
+	for rows.Next() {
+		var (
+			row       orders.StatsRow
+			ratedText *string
+		)
+		if err := rows.Scan(&row.Key, &row.Currency, &row.Orders, &row.Items, &row.Revenue, &ratedText, &row.MissingRates); err != nil {
+			return nil, loadError(ctx, "Error scanning stats row", err, len(out))
+		}
+		if ratedText != nil {
+			v, ok := new(big.Rat).SetString(*ratedText)
+			if !ok {
+				return nil, fmt.Errorf("unparsable rated revenue %q", *ratedText)
+			}
+			row.Rated = v
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, loadError(ctx, "Error iterating over stats rows", err, len(out))
+	}
+
+	logging.LogInfo("Order stats computed", logrus.Fields{"group_by": req.GroupBy, "rows": len(out), "reporting_currency": req.ReportingCurrency})
+	return out, nil
+}
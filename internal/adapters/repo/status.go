@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const (
+	qChangeStatus = `UPDATE orders SET status = $2, version = version + 1 WHERE order_uid = $1;`
+
+	qInsertStatusHistory = `
+INSERT INTO order_status_history (order_uid, from_status, to_status, reason, changed_at)
+VALUES ($1,$2,$3,$4,$5);`
+
+	qStatusHistory = `
+SELECT order_uid, from_status, to_status, reason, changed_at
+FROM order_status_history
+WHERE order_uid = $1
+ORDER BY id;`
+)
+
+// ChangeStatus applies ch only if the order is still in ch.From, so a
+// concurrent transition makes it fail with orders.ErrConflict.
+func (r *OrderRepo) ChangeStatus(ctx context.Context, ch order.StatusChange, events ...orders.OutboxEvent) error {
+	fields := logrus.Fields{"order_uid": ch.OrderUID, "from": ch.From, "to": ch.To}
+	logging.LogInfo("Attempting to change order status", fields)
+
+	tx, err := r.repo.Begin(ctx)
+	if err != nil {
+		logging.LogError("Error starting transaction", err, fields)
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	locked, err := lockOrder(ctx, tx, ch.OrderUID)
+	if err == nil && locked.DeletedAt != nil {
+		err = orders.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, orders.ErrNotFound) {
+			logging.LogError("Order not found to change status", nil, fields)
+		}
+		return err
+	}
+	if locked.Status != ch.From {
+		logging.LogError("Order status changed concurrently", nil, fields)
+		return orders.ErrConflict
+	}
+
+	if _, err := tx.Exec(ctx, qChangeStatus, ch.OrderUID, string(ch.To)); err != nil {
+		logging.LogError("Error updating order status", err, fields)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return orders.ErrTimeout
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, qInsertStatusHistory,
+		ch.OrderUID, string(ch.From), string(ch.To), ch.Reason, ch.ChangedAt,
+	); err != nil {
+		logging.LogError("Error inserting status history", err, fields)
+		return err
+	}
+
+	snapshot, err := getOrder(ctx, tx, ch.OrderUID)
+	if err != nil {
+		logging.LogError("Error loading order snapshot after status change", err, fields)
+		return err
+	}
+	if err := insertRevision(ctx, tx, order.RevisionStatusChange, snapshot); err != nil {
+		return err
+	}
+	if err := insertOutbox(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := saveContextOffset(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logging.LogError("Error committing transaction", err, fields)
+		return err
+	}
+
+	logging.LogInfo("Order status changed", fields)
+	return nil
+}
+
+func (r *OrderRepo) StatusHistory(ctx context.Context, uid string) ([]order.StatusChange, error) {
+	rows, err := r.repo.Query(ctx, qStatusHistory, uid)
+	if err != nil {
+		logging.LogError("Error fetching status history", err, logrus.Fields{"order_uid": uid})
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]order.StatusChange, 0, 8)
+	for rows.Next() {
+		var (
+			ch       order.StatusChange
+			from, to string
+		)
+		if err := rows.Scan(&ch.OrderUID, &from, &to, &ch.Reason, &ch.ChangedAt); err != nil {
+			logging.LogError("Error scanning status history", err, logrus.Fields{"order_uid": uid})
+			return nil, err
+		}
+		ch.From, ch.To = order.Status(from), order.Status(to)
+		out = append(out, ch)
+	}
+	if err := rows.Err(); err != nil {
+		logging.LogError("Error iterating status history", err, logrus.Fields{"order_uid": uid})
+		return nil, err
+	}
+	return out, nil
+}
@@ -12,7 +12,13 @@ CREATE TABLE IF NOT EXISTS orders (
                                       shard_key           TEXT        NOT NULL,
                                       sm_id               BIGINT      NOT NULL,
                                       date_created        TIMESTAMPTZ NOT NULL DEFAULT now(),
-    oof_shard           BIGINT      NOT NULL
+    oof_shard           BIGINT      NOT NULL,
+    status              TEXT        NOT NULL DEFAULT 'created',
+    version             BIGINT      NOT NULL DEFAULT 1,
+    deleted_at          TIMESTAMPTZ NULL,
+    CONSTRAINT chk_orders_version_positive CHECK (version > 0),
+    CONSTRAINT chk_orders_status
+        CHECK (status IN ('created', 'paid', 'assembling', 'shipped', 'delivered', 'cancelled', 'returned'))
     );
 
 CREATE TABLE IF NOT EXISTS deliveries (
@@ -96,3 +102,179 @@ CREATE INDEX IF NOT EXISTS idx_items_track_number ON order_items (track_number);
 CREATE INDEX IF NOT EXISTS idx_orders_track_trgm ON orders USING gin (track_number gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_orders_uid_trgm   ON orders USING gin (order_uid    gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_payments_tx_trgm  ON payments USING gin (transaction gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGSERIAL   PRIMARY KEY,
+    aggregate_id    TEXT        NOT NULL,
+    topic           TEXT        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    msg_key         BYTEA,
+    payload         JSONB       NOT NULL,
+    headers         JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at         TIMESTAMPTZ,
+    CONSTRAINT chk_outbox_status CHECK (status IN ('pending', 'sent', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate_pending ON outbox (aggregate_id, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON outbox (sent_at) WHERE status = 'sent';
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id          BIGSERIAL   PRIMARY KEY,
+    order_uid   TEXT        NOT NULL
+        REFERENCES orders(order_uid) ON UPDATE CASCADE ON DELETE CASCADE,
+    from_status TEXT        NOT NULL,
+    to_status   TEXT        NOT NULL,
+    reason      TEXT        NOT NULL DEFAULT '',
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history (order_uid, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+
+CREATE TABLE IF NOT EXISTS order_revisions (
+    order_uid  TEXT        NOT NULL,
+    rev        BIGINT      NOT NULL,
+    op         TEXT        NOT NULL,
+    snapshot   JSONB       NOT NULL,
+    actor      TEXT        NOT NULL DEFAULT '',
+    request_id TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT pk_order_revisions PRIMARY KEY (order_uid, rev),
+    CONSTRAINT chk_order_revisions_op CHECK (op IN ('create', 'update', 'delete', 'restore', 'status_change'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_revisions_created_at ON order_revisions (created_at);
+
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base       TEXT           NOT NULL,
+    quote      TEXT           NOT NULL,
+    valid_from TIMESTAMPTZ    NOT NULL,
+    rate       NUMERIC(24,12) NOT NULL,
+    loaded_at  TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    CONSTRAINT pk_exchange_rates PRIMARY KEY (base, quote, valid_from),
+    CONSTRAINT chk_exchange_rates_base_iso3  CHECK (base  ~ '^[A-Z]{3}$'),
+    CONSTRAINT chk_exchange_rates_quote_iso3 CHECK (quote ~ '^[A-Z]{3}$'),
+    CONSTRAINT chk_exchange_rates_rate_pos   CHECK (rate > 0)
+);
+
+-- One search document per order, kept in sync by triggers. haystack is the
+-- plain text for trigram matching; document weighs identifiers (A) over
+-- item names and brands (B) over delivery name and city (C).
+CREATE TABLE IF NOT EXISTS order_search_docs (
+    order_uid  TEXT        PRIMARY KEY
+        REFERENCES orders(order_uid) ON UPDATE CASCADE ON DELETE CASCADE,
+    haystack   TEXT        NOT NULL,
+    document   TSVECTOR    NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_search_docs_document ON order_search_docs USING gin (document);
+CREATE INDEX IF NOT EXISTS idx_order_search_docs_haystack ON order_search_docs USING gin (haystack gin_trgm_ops);
+
+CREATE OR REPLACE FUNCTION refresh_order_search_doc(uid TEXT) RETURNS void AS $$
+DECLARE
+    ids      TEXT;
+    goods    TEXT;
+    delivery TEXT;
+BEGIN
+    SELECT concat_ws(' ', o.order_uid, o.track_number, o.customer_id, p.transaction)
+      INTO ids
+      FROM orders o
+      LEFT JOIN payments p ON p.order_uid = o.order_uid
+     WHERE o.order_uid = uid;
+    IF NOT FOUND THEN
+        DELETE FROM order_search_docs WHERE order_uid = uid;
+        RETURN;
+    END IF;
+
+    SELECT string_agg(concat_ws(' ', i.item_name, i.brand), ' ' ORDER BY i.chrt_id)
+      INTO goods
+      FROM order_items i
+     WHERE i.order_uid = uid;
+
+    SELECT concat_ws(' ', d.delivery_name, d.city)
+      INTO delivery
+      FROM deliveries d
+     WHERE d.order_uid = uid;
+
+    INSERT INTO order_search_docs (order_uid, haystack, document, updated_at)
+    VALUES (
+        uid,
+        concat_ws(' ', ids, goods, delivery),
+        setweight(to_tsvector('simple', coalesce(ids, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(goods, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(delivery, '')), 'C'),
+        now()
+    )
+    ON CONFLICT (order_uid) DO UPDATE SET
+        haystack   = EXCLUDED.haystack,
+        document   = EXCLUDED.document,
+        updated_at = EXCLUDED.updated_at;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trg_refresh_order_search_doc() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM refresh_order_search_doc(OLD.order_uid);
+    END IF;
+    IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.order_uid IS DISTINCT FROM OLD.order_uid) THEN
+        PERFORM refresh_order_search_doc(NEW.order_uid);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_orders_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, track_number, customer_id ON orders
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+CREATE TRIGGER trg_payments_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, transaction OR DELETE ON payments
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+CREATE TRIGGER trg_deliveries_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, delivery_name, city OR DELETE ON deliveries
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+CREATE TRIGGER trg_order_items_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, item_name, brand OR DELETE ON order_items
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+-- Support lookups by item, delivery and amount; brand, city, region and email
+-- are searched case-insensitively.
+CREATE INDEX IF NOT EXISTS idx_items_nm_id       ON order_items (nm_id);
+CREATE INDEX IF NOT EXISTS idx_items_chrt_id     ON order_items (chrt_id);
+CREATE INDEX IF NOT EXISTS idx_items_rid         ON order_items (rid);
+CREATE INDEX IF NOT EXISTS idx_items_brand_lower ON order_items (lower(brand));
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_city_lower   ON deliveries (lower(city));
+CREATE INDEX IF NOT EXISTS idx_deliveries_region_lower ON deliveries (lower(region));
+CREATE INDEX IF NOT EXISTS idx_deliveries_phone        ON deliveries (phone);
+CREATE INDEX IF NOT EXISTS idx_deliveries_email_lower  ON deliveries (lower(email));
+
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service ON orders (delivery_service);
+CREATE INDEX IF NOT EXISTS idx_payments_amount         ON payments (amount);
+
+-- Idempotency-Key of POST /orders: the fingerprint of the first request and,
+-- once it has finished, the response replayed to its retries. status_code is
+-- NULL while that request is in flight.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          TEXT PRIMARY KEY CHECK (length(key) BETWEEN 1 AND 255),
+    fingerprint  TEXT NOT NULL,
+    status_code  INT,
+    headers      JSONB,
+    body         BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
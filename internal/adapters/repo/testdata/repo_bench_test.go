@@ -0,0 +1,104 @@
+package repo_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/reybrally/order-service/internal/adapters/repo"
+)
+
+const (
+	benchOrders = 64
+	// benchMinSpeedup is the throughput gain of the largest pool over a pool
+	// of one connection below which the benchmark fails.
+	benchMinSpeedup = 2.0
+)
+
+// BenchmarkRepo_Concurrent runs a mixed read/write load (3 GetOrder per
+// CreateOrUpdateOrder) over a fixed set of orders with growing pool sizes. It
+// reports throughput and the mean latency of reads and writes per pool size,
+// and fails if throughput does not grow with the pool.
+func BenchmarkRepo_Concurrent(b *testing.B) {
+	sizes := []int32{1, 4, 16}
+	throughput := make(map[int32]float64, len(sizes))
+	for _, size := range sizes {
+		b.Run(fmt.Sprintf("pool=%d", size), func(b *testing.B) {
+			cfg, err := pgxpool.ParseConfig(testDSN())
+			if err != nil {
+				b.Fatalf("ParseConfig: %v", err)
+			}
+			setup := newTestPool(b)
+			truncateAll(b, setup)
+			setup.Close()
+
+			cfg.MaxConns = size
+			pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+			if err != nil {
+				b.Fatalf("pgxpool.NewWithConfig: %v", err)
+			}
+			b.Cleanup(pool.Close)
+			r := repo.NewOrderRepo(pool)
+
+			ctx := context.Background()
+			uids := make([]string, benchOrders)
+			for i := range uids {
+				uids[i] = fmt.Sprintf("bench-%d", i)
+				if _, err := r.CreateOrUpdateOrder(ctx, makeOrder(uids[i], true)); err != nil {
+					b.Fatalf("seed %s: %v", uids[i], err)
+				}
+			}
+
+			var next, reads, writes, readNs, writeNs atomic.Int64
+			b.SetParallelism(int(size))
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := next.Add(1)
+					uid := uids[n%benchOrders]
+					start := time.Now()
+					if n%4 == 0 {
+						if _, err := r.CreateOrUpdateOrder(ctx, makeOrder(uid, n%8 == 0)); err != nil {
+							b.Errorf("CreateOrUpdateOrder %s: %v", uid, err)
+							return
+						}
+						writes.Add(1)
+						writeNs.Add(int64(time.Since(start)))
+						continue
+					}
+					if _, err := r.GetOrder(ctx, uid); err != nil {
+						b.Errorf("GetOrder %s: %v", uid, err)
+						return
+					}
+					reads.Add(1)
+					readNs.Add(int64(time.Since(start)))
+				}
+			})
+			b.StopTimer()
+
+			opsPerSec := float64(b.N) / b.Elapsed().Seconds()
+			throughput[size] = opsPerSec
+			b.ReportMetric(opsPerSec, "ops/s")
+			if n := reads.Load(); n > 0 {
+				b.ReportMetric(float64(readNs.Load())/float64(n), "read-ns/op")
+			}
+			if n := writes.Load(); n > 0 {
+				b.ReportMetric(float64(writeNs.Load())/float64(n), "write-ns/op")
+			}
+		})
+	}
+
+	first, last := sizes[0], sizes[len(sizes)-1]
+	if throughput[first] == 0 || throughput[last] == 0 {
+		return
+	}
+	speedup := throughput[last] / throughput[first]
+	b.Logf("throughput pool=%d / pool=%d: %.2fx", last, first, speedup)
+	if speedup < benchMinSpeedup {
+		b.Errorf("throughput does not scale with the pool: %.2fx from pool=%d to pool=%d, want at least %.1fx",
+			speedup, first, last, benchMinSpeedup)
+	}
+}
@@ -2,20 +2,35 @@ package repo_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/reybrally/order-service/internal/adapters/repo"
 	app "github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
 	"github.com/reybrally/order-service/internal/domain/order"
 )
 
-func newTestPool(t *testing.T) *pgxpool.Pool {
+// defaultTestDSN is the local database of docker-compose and make db-init.
+const defaultTestDSN = "user=postgres password=postgres dbname=orders_db host=127.0.0.1 port=55432 sslmode=disable"
+
+// testDSN is the test database from TEST_DATABASE_URL, or the local one. The
+// tests fail rather than skip when it is unreachable.
+func testDSN() string {
+	if dsn := os.Getenv("TEST_DATABASE_URL"); dsn != "" {
+		return dsn
+	}
+	return defaultTestDSN
+}
+
+func newTestPool(t testing.TB) *pgxpool.Pool {
 	t.Helper()
-	pool, err := pgxpool.New(context.Background(),
-		"user=postgres password=postgres dbname=orders_db host=127.0.0.1 port=55432 sslmode=disable")
+	pool, err := pgxpool.New(context.Background(), testDSN())
 	if err != nil {
 		t.Fatalf("pgxpool.New: %v", err)
 	}
@@ -30,7 +45,7 @@ func newTestRepo(t *testing.T) (*repo.OrderRepo, *pgxpool.Pool) {
 	return r, pool
 }
 
-func truncateAll(t *testing.T, pool *pgxpool.Pool) {
+func truncateAll(t testing.TB, pool *pgxpool.Pool) {
 	t.Helper()
 	ctx := context.Background()
 	_, err := pool.Exec(ctx, `
@@ -38,6 +53,10 @@ func truncateAll(t *testing.T, pool *pgxpool.Pool) {
 		TRUNCATE TABLE payments     RESTART IDENTITY CASCADE;
 		TRUNCATE TABLE deliveries   RESTART IDENTITY CASCADE;
 		TRUNCATE TABLE orders       RESTART IDENTITY CASCADE;
+		TRUNCATE TABLE order_revisions;
+		TRUNCATE TABLE exchange_rates;
+		TRUNCATE TABLE outbox;
+		TRUNCATE TABLE idempotency_keys;
 	`)
 	if err != nil {
 		t.Fatalf("truncateAll: %v", err)
@@ -123,6 +142,11 @@ func mustSetDate(t *testing.T, pool *pgxpool.Pool, uid string, ts time.Time) {
 
 func ptrTime(ti time.Time) *time.Time { return &ti }
 
+func searchOrders(ctx context.Context, r *repo.OrderRepo, f app.SearchFilters, p app.PageRequest) ([]order.Order, error) {
+	page, err := r.SearchOrders(ctx, f, p)
+	return page.Orders, err
+}
+
 func TestRepo_CreateOrUpdate_then_FindByUID_and_ItemsTrim(t *testing.T) {
 	r, pool := newTestRepo(t)
 	ctx := context.Background()
@@ -212,7 +236,7 @@ func TestRepo_SearchOrders_SortingByDateCreated(t *testing.T) {
 	}
 	page := app.PageRequest{Limit: 10, Offset: 0, SortBy: "date_created", SortDir: "DESC"}
 
-	got, err := r.SearchOrders(ctx, f, page)
+	got, err := searchOrders(ctx, r, f, page)
 	if err != nil {
 		t.Fatalf("SearchOrders: %v", err)
 	}
@@ -246,7 +270,7 @@ func TestRepo_SearchOrders_ByIndividualFilters(t *testing.T) {
 		t.Fatalf("create C: %v", err)
 	}
 
-	res, err := r.SearchOrders(ctx, app.SearchFilters{OrderUID: &a.OrderUID}, app.PageRequest{Limit: 10})
+	res, err := searchOrders(ctx, r, app.SearchFilters{OrderUID: &a.OrderUID}, app.PageRequest{Limit: 10})
 	if err != nil {
 		t.Fatalf("Search by UID: %v", err)
 	}
@@ -255,30 +279,30 @@ func TestRepo_SearchOrders_ByIndividualFilters(t *testing.T) {
 	}
 
 	sub := "TRK-"
-	res, err = r.SearchOrders(ctx, app.SearchFilters{TrackNumber: &sub}, app.PageRequest{Limit: 10})
+	res, err = searchOrders(ctx, r, app.SearchFilters{TrackNumber: &sub}, app.PageRequest{Limit: 10})
 	if err != nil || len(res) != 3 {
 		t.Fatalf("Search by track substring: err=%v len=%d", err, len(res))
 	}
 
-	res, err = r.SearchOrders(ctx, app.SearchFilters{CustomerID: &b.CustomerId}, app.PageRequest{Limit: 10})
+	res, err = searchOrders(ctx, r, app.SearchFilters{CustomerID: &b.CustomerId}, app.PageRequest{Limit: 10})
 	if err != nil || len(res) != 1 || res[0].OrderUID != b.OrderUID {
 		t.Fatalf("Search by customer: %v / %#v", err, res)
 	}
 
 	prov := "paypal"
-	res, err = r.SearchOrders(ctx, app.SearchFilters{Provider: &prov}, app.PageRequest{Limit: 10})
+	res, err = searchOrders(ctx, r, app.SearchFilters{Provider: &prov}, app.PageRequest{Limit: 10})
 	if err != nil || len(res) != 1 || res[0].OrderUID != c.OrderUID {
 		t.Fatalf("Search by provider: %v / %#v", err, res)
 	}
 
 	cur := "USD"
-	res, err = r.SearchOrders(ctx, app.SearchFilters{Currency: &cur}, app.PageRequest{Limit: 10})
+	res, err = searchOrders(ctx, r, app.SearchFilters{Currency: &cur}, app.PageRequest{Limit: 10})
 	if err != nil || len(res) != 2 {
 		t.Fatalf("Search by currency USD expected 2, got %d", len(res))
 	}
 
 	q := a.Payment.Transaction
-	res, err = r.SearchOrders(ctx, app.SearchFilters{Query: &q}, app.PageRequest{Limit: 10})
+	res, err = searchOrders(ctx, r, app.SearchFilters{Query: &q}, app.PageRequest{Limit: 10})
 	if err != nil || len(res) != 1 || res[0].OrderUID != a.OrderUID {
 		t.Fatalf("Search by query(transaction): %v / %#v", err, res)
 	}
@@ -292,3 +316,936 @@ func TestRepo_DeleteOrder_NotFound(t *testing.T) {
 		t.Fatalf("expected not found on delete")
 	}
 }
+
+func TestRepo_CreateOrUpdate_ExpectedVersion(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	created, err := r.CreateOrUpdateOrder(ctx, makeOrder("uid-ver", false))
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOrder: %v", err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("expected version 1 after insert, got %d", created.Version)
+	}
+
+	upd := makeOrder("uid-ver", false)
+	upd.Version = created.Version
+	updated, err := r.CreateOrUpdateOrder(ctx, upd)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOrder(version=1): %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("expected version 2 after update, got %d", updated.Version)
+	}
+
+	stale := makeOrder("uid-ver", false)
+	stale.Version = created.Version
+	if _, err := r.CreateOrUpdateOrder(ctx, stale); !errors.Is(err, app.ErrConflict) {
+		t.Fatalf("expected ErrConflict for stale version, got %v", err)
+	}
+
+	missing := makeOrder("uid-ver-missing", false)
+	missing.Version = 1
+	if _, err := r.CreateOrUpdateOrder(ctx, missing); !errors.Is(err, app.ErrConflict) {
+		t.Fatalf("expected ErrConflict for expected version on a new order, got %v", err)
+	}
+
+	// If-Match: * only requires the order to exist.
+	anyVer := makeOrder("uid-ver", false)
+	anyVer.Version = order.AnyVersion
+	if got, err := r.CreateOrUpdateOrder(ctx, anyVer); err != nil || got.Version != 3 {
+		t.Fatalf("CreateOrUpdateOrder(any version): version=%d err=%v", got.Version, err)
+	}
+	missing.Version = order.AnyVersion
+	if _, err := r.CreateOrUpdateOrder(ctx, missing); !errors.Is(err, app.ErrConflict) {
+		t.Fatalf("expected ErrConflict for any version on a new order, got %v", err)
+	}
+}
+
+func TestRepo_Revisions_SurviveDelete(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := app.WithActor(context.Background(), app.Actor{Name: "tester", RequestID: "req-1"})
+
+	if _, err := r.CreateOrUpdateOrder(ctx, makeOrder("uid-rev", true)); err != nil {
+		t.Fatalf("CreateOrUpdateOrder: %v", err)
+	}
+	if _, err := r.CreateOrUpdateOrder(ctx, makeOrder("uid-rev", false)); err != nil {
+		t.Fatalf("CreateOrUpdateOrder(update): %v", err)
+	}
+	if err := r.DeleteOrder(ctx, "uid-rev"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+
+	list, err := r.Revisions(ctx, "uid-rev")
+	if err != nil {
+		t.Fatalf("Revisions: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(list))
+	}
+	wantOps := []order.RevisionOp{order.RevisionCreate, order.RevisionUpdate, order.RevisionDelete}
+	for i, rev := range list {
+		if rev.Rev != int64(i+1) || rev.Op != wantOps[i] {
+			t.Fatalf("revision %d: got rev=%d op=%s", i, rev.Rev, rev.Op)
+		}
+		if rev.Actor != "tester" || rev.RequestID != "req-1" {
+			t.Fatalf("revision %d: unexpected actor %q / request %q", i, rev.Actor, rev.RequestID)
+		}
+	}
+
+	first, err := r.Revision(ctx, "uid-rev", 1)
+	if err != nil {
+		t.Fatalf("Revision(1): %v", err)
+	}
+	if first.Snapshot == nil || len(first.Snapshot.Items) != 2 {
+		t.Fatalf("expected snapshot with 2 items in first revision, got %+v", first.Snapshot)
+	}
+
+	if _, err := r.Revision(ctx, "uid-rev", 42); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for missing revision, got %v", err)
+	}
+}
+
+func TestRepo_SoftDelete_RestoreAndPurge(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := r.CreateOrUpdateOrder(ctx, makeOrder("uid-soft", true)); err != nil {
+		t.Fatalf("CreateOrUpdateOrder: %v", err)
+	}
+	if err := r.DeleteOrder(ctx, "uid-soft"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	if _, err := r.GetOrder(ctx, "uid-soft"); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for deleted order, got %v", err)
+	}
+	if err := r.DeleteOrder(ctx, "uid-soft"); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound on second delete, got %v", err)
+	}
+	if _, err := r.CreateOrUpdateOrder(ctx, makeOrder("uid-soft", true)); !errors.Is(err, app.ErrConflict) {
+		t.Fatalf("expected ErrConflict when upserting a deleted order, got %v", err)
+	}
+
+	uid := "uid-soft"
+	list, err := searchOrders(ctx, r, app.SearchFilters{OrderUID: &uid}, app.PageRequest{})
+	if err != nil {
+		t.Fatalf("SearchOrders: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("expected deleted order to be hidden from search, got %d", len(list))
+	}
+	list, err = searchOrders(ctx, r, app.SearchFilters{OrderUID: &uid, IncludeDeleted: true}, app.PageRequest{})
+	if err != nil {
+		t.Fatalf("SearchOrders(include_deleted): %v", err)
+	}
+	if len(list) != 1 || list[0].DeletedAt == nil {
+		t.Fatalf("expected one deleted order with include_deleted, got %+v", list)
+	}
+
+	restored, err := r.RestoreOrder(ctx, "uid-soft")
+	if err != nil {
+		t.Fatalf("RestoreOrder: %v", err)
+	}
+	if restored.DeletedAt != nil || len(restored.Items) != 2 {
+		t.Fatalf("unexpected restored order: %+v", restored)
+	}
+	if _, err := r.RestoreOrder(ctx, "uid-soft"); !errors.Is(err, app.ErrConflict) {
+		t.Fatalf("expected ErrConflict restoring a live order, got %v", err)
+	}
+
+	if err := r.DeleteOrder(ctx, "uid-soft"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	n, err := r.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("PurgeDeleted: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 purged order, got %d", n)
+	}
+	if _, err := r.RestoreOrder(ctx, "uid-soft"); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after purge, got %v", err)
+	}
+}
+
+func TestRepo_SearchOrders_CursorPagination(t *testing.T) {
+	r, pool := newTestRepo(t)
+	ctx := context.Background()
+
+	base := time.Date(2021, 11, 26, 6, 0, 0, 0, time.UTC)
+	for i, uid := range []string{"cur-a", "cur-b", "cur-c", "cur-d", "cur-e"} {
+		if _, err := r.CreateOrUpdateOrder(ctx, makeOrder(uid, false)); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", uid, err)
+		}
+		// cur-b and cur-c share a timestamp to exercise the order_uid tie-breaker.
+		ts := base.Add(time.Duration(i) * time.Hour)
+		if uid == "cur-c" {
+			ts = base.Add(time.Hour)
+		}
+		mustSetDate(t, pool, uid, ts)
+	}
+
+	var (
+		seen   []string
+		cursor string
+	)
+	for pages := 0; pages < 10; pages++ {
+		page, err := r.SearchOrders(ctx, app.SearchFilters{}, app.PageRequest{
+			Limit: 2, SortBy: "date_created", SortDir: "desc", Cursor: cursor,
+		})
+		if err != nil {
+			t.Fatalf("SearchOrders page %d: %v", pages, err)
+		}
+		for _, o := range page.Orders {
+			seen = append(seen, o.OrderUID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	want := []string{"cur-e", "cur-d", "cur-c", "cur-b", "cur-a"}
+	if strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, seen)
+	}
+
+	if _, err := r.SearchOrders(ctx, app.SearchFilters{}, app.PageRequest{
+		Limit: 2, SortBy: "amount", SortDir: "desc", Cursor: cursor,
+	}); !errors.Is(err, app.ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for a cursor of another sort, got %v", err)
+	}
+}
+
+func TestRepo_GetOrders_PreservesOrderAndSkipsMissing(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, o := range []order.Order{makeOrder("batch-a", true), makeOrder("batch-b", false), makeOrder("batch-c", true)} {
+		if _, err := r.CreateOrUpdateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", o.OrderUID, err)
+		}
+	}
+	if err := r.DeleteOrder(ctx, "batch-b"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+
+	got, err := r.GetOrders(ctx, []string{"batch-c", "missing", "batch-b", "batch-a"})
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	if len(got) != 2 || got[0].OrderUID != "batch-c" || got[1].OrderUID != "batch-a" {
+		t.Fatalf("unexpected orders: %+v", got)
+	}
+	for _, o := range got {
+		if len(o.Items) != 2 || o.Payment.Transaction == "" || o.Delivery.Name == "" {
+			t.Fatalf("order %s not fully hydrated: %+v", o.OrderUID, o)
+		}
+	}
+}
+
+func TestRepo_ChangeStatus_ConcurrentWritersConflict(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := r.CreateOrUpdateOrder(ctx, makeOrder("uid-race", false)); err != nil {
+		t.Fatalf("CreateOrUpdateOrder: %v", err)
+	}
+
+	const writers = 8
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		go func() {
+			errs <- r.ChangeStatus(ctx, order.StatusChange{
+				OrderUID: "uid-race", From: order.StatusCreated, To: order.StatusPaid, ChangedAt: time.Now().UTC(),
+			})
+		}()
+	}
+
+	var ok, conflicts int
+	for i := 0; i < writers; i++ {
+		switch err := <-errs; {
+		case err == nil:
+			ok++
+		case errors.Is(err, app.ErrConflict):
+			conflicts++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if ok != 1 || conflicts != writers-1 {
+		t.Fatalf("expected exactly one winner, got ok=%d conflicts=%d", ok, conflicts)
+	}
+
+	hist, err := r.StatusHistory(ctx, "uid-race")
+	if err != nil {
+		t.Fatalf("StatusHistory: %v", err)
+	}
+	if len(hist) != 1 {
+		t.Fatalf("expected a single history entry, got %d", len(hist))
+	}
+}
+
+func TestRepo_InconsistentOrders_MatchesDomainCheck(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	consistent := makeOrder("fin-a", true)
+	consistent.Payment.GoodsTotal, consistent.Payment.Amount = 300, 350
+
+	badItem := makeOrder("fin-b", false)
+	badItem.Items[0].Sale = 10
+	badItem.Payment.GoodsTotal, badItem.Payment.Amount = 100, 150
+
+	badAmount := makeOrder("fin-c", false)
+	badAmount.Payment.GoodsTotal, badAmount.Payment.Amount = 100, 100
+
+	deleted := makeOrder("fin-d", false)
+
+	for _, o := range []order.Order{consistent, badItem, badAmount, deleted} {
+		if _, err := r.CreateOrUpdateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", o.OrderUID, err)
+		}
+	}
+	if err := r.DeleteOrder(ctx, "fin-d"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+
+	first, err := r.InconsistentOrders(ctx, "", 1)
+	if err != nil {
+		t.Fatalf("InconsistentOrders: %v", err)
+	}
+	if len(first.Orders) != 1 || first.Orders[0].OrderUID != "fin-b" || first.NextCursor != "fin-b" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+	if issues := first.Orders[0].CheckConsistency(); len(issues) != 1 || issues[0].Code != order.CodeItemTotalMismatch {
+		t.Fatalf("unexpected issues for fin-b: %+v", issues)
+	}
+
+	second, err := r.InconsistentOrders(ctx, first.NextCursor, 1)
+	if err != nil {
+		t.Fatalf("InconsistentOrders: %v", err)
+	}
+	if len(second.Orders) != 1 || second.Orders[0].OrderUID != "fin-c" || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+	if issues := second.Orders[0].CheckConsistency(); len(issues) != 1 || issues[0].Code != order.CodeAmountMismatch {
+		t.Fatalf("unexpected issues for fin-c: %+v", issues)
+	}
+}
+
+func TestRepo_ExchangeRates_RateInEffectAt(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	rates := repo.NewExchangeRateRepo(pool)
+
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	mustRate := func(base, value string, from time.Time) money.Rate {
+		rt, err := money.ParseRate(base, "USD", value, from)
+		if err != nil {
+			t.Fatalf("ParseRate: %v", err)
+		}
+		return rt
+	}
+	if err := rates.SaveRates(ctx, []money.Rate{
+		mustRate("EUR", "1.05", day(1)),
+		mustRate("EUR", "1.10", day(10)),
+		mustRate("RUB", "0.011", day(1)),
+	}); err != nil {
+		t.Fatalf("SaveRates: %v", err)
+	}
+	// Reloading a pair and date replaces the rate.
+	if err := rates.SaveRates(ctx, []money.Rate{mustRate("EUR", "1.08", day(10))}); err != nil {
+		t.Fatalf("SaveRates: %v", err)
+	}
+
+	got, err := rates.RatesAt(ctx, "USD", []app.RateRequest{
+		{Base: "EUR", At: day(5)},
+		{Base: "GBP", At: day(5)},
+		{Base: "EUR", At: day(10).Add(time.Hour)},
+		{Base: "RUB", At: day(1).Add(-time.Second)},
+	})
+	if err != nil {
+		t.Fatalf("RatesAt: %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("want 4 rates, got %d", len(got))
+	}
+	if got[0].Value == nil || got[0].Decimal() != "1.05" || !got[0].ValidFrom.Equal(day(1)) {
+		t.Fatalf("EUR at day 5: %+v", got[0])
+	}
+	if got[1].Value != nil {
+		t.Fatalf("GBP must have no rate: %+v", got[1])
+	}
+	if got[2].Value == nil || got[2].Decimal() != "1.08" {
+		t.Fatalf("EUR at day 10: %+v", got[2])
+	}
+	if got[3].Value != nil {
+		t.Fatalf("RUB before its first rate must have no rate: %+v", got[3])
+	}
+}
+
+func TestRepo_OrderStats_GroupsAndConverts(t *testing.T) {
+	r, pool := newTestRepo(t)
+	ctx := context.Background()
+
+	eur := makeOrder("stats-c", false)
+	eur.Payment.Currency = "EUR"
+	for _, o := range []order.Order{makeOrder("stats-a", true), makeOrder("stats-b", false), eur} {
+		if _, err := r.CreateOrUpdateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", o.OrderUID, err)
+		}
+	}
+
+	byCurrency, err := r.OrderStats(ctx, app.SearchFilters{}, app.StatsRequest{GroupBy: app.StatsByCurrency})
+	if err != nil {
+		t.Fatalf("OrderStats by currency: %v", err)
+	}
+	if len(byCurrency) != 2 ||
+		byCurrency[0].Key != "EUR" || byCurrency[0].Orders != 1 || byCurrency[0].Items != 1 || byCurrency[0].Revenue != 300 ||
+		byCurrency[1].Key != "USD" || byCurrency[1].Orders != 2 || byCurrency[1].Items != 3 || byCurrency[1].Revenue != 600 {
+		t.Fatalf("unexpected stats by currency: %+v", byCurrency)
+	}
+
+	byBrand, err := r.OrderStats(ctx, app.SearchFilters{}, app.StatsRequest{GroupBy: app.StatsByBrand})
+	if err != nil {
+		t.Fatalf("OrderStats by brand: %v", err)
+	}
+	// brand-1/EUR, brand-1/USD, brand-2/USD; revenue is the item totals.
+	if len(byBrand) != 3 || byBrand[1].Key != "brand-1" || byBrand[1].Orders != 2 || byBrand[1].Revenue != 200 ||
+		byBrand[2].Key != "brand-2" || byBrand[2].Orders != 1 || byBrand[2].Revenue != 200 {
+		t.Fatalf("unexpected stats by brand: %+v", byBrand)
+	}
+
+	rate, err := money.ParseRate("EUR", "USD", "1.1", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ParseRate: %v", err)
+	}
+	if err := repo.NewExchangeRateRepo(pool).SaveRates(ctx, []money.Rate{rate}); err != nil {
+		t.Fatalf("SaveRates: %v", err)
+	}
+	inUSD, err := r.OrderStats(ctx, app.SearchFilters{}, app.StatsRequest{GroupBy: app.StatsByDeliveryService, ReportingCurrency: "USD"})
+	if err != nil {
+		t.Fatalf("OrderStats in USD: %v", err)
+	}
+	if len(inUSD) != 2 || inUSD[0].Rated == nil || inUSD[0].Rated.FloatString(1) != "330.0" || inUSD[0].MissingRates != 0 ||
+		inUSD[1].Rated == nil || inUSD[1].Rated.FloatString(1) != "600.0" {
+		t.Fatalf("unexpected stats in USD: %+v", inUSD)
+	}
+
+	inJPY, err := r.OrderStats(ctx, app.SearchFilters{}, app.StatsRequest{GroupBy: app.StatsByDeliveryService, ReportingCurrency: "JPY"})
+	if err != nil {
+		t.Fatalf("OrderStats in JPY: %v", err)
+	}
+	if len(inJPY) != 2 || inJPY[0].MissingRates != 1 || inJPY[1].MissingRates != 2 {
+		t.Fatalf("expected missing rates: %+v", inJPY)
+	}
+
+	usd := "USD"
+	filtered, err := r.OrderStats(ctx, app.SearchFilters{Currency: &usd}, app.StatsRequest{GroupBy: app.StatsByDay})
+	if err != nil {
+		t.Fatalf("OrderStats filtered: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].Orders != 2 {
+		t.Fatalf("unexpected filtered stats: %+v", filtered)
+	}
+}
+
+func TestRepo_SearchOrders_FullTextAndFuzzy(t *testing.T) {
+	r, pool := newTestRepo(t)
+	ctx := context.Background()
+
+	a := makeOrder("fts-a", false)
+	a.Items[0].Name, a.Items[0].Brand = "Mascaras", "Vivienne Sabo"
+	b := makeOrder("fts-b", false)
+	b.Items[0].Name, b.Items[0].Brand = "Lipstick", "Sabo"
+	b.Delivery.City = "Kiryat Mozkin"
+	for _, o := range []order.Order{a, b} {
+		if _, err := r.CreateOrUpdateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", o.OrderUID, err)
+		}
+	}
+
+	search := func(q string, p app.PageRequest) []order.Order {
+		t.Helper()
+		p.Limit = 10
+		res, err := searchOrders(ctx, r, app.SearchFilters{Query: &q}, p)
+		if err != nil {
+			t.Fatalf("Search %q: %v", q, err)
+		}
+		return res
+	}
+
+	if res := search("vivienne", app.PageRequest{}); len(res) != 1 || res[0].OrderUID != a.OrderUID {
+		t.Fatalf("brand word: %#v", res)
+	}
+	if res := search("mascara", app.PageRequest{}); len(res) != 1 || res[0].OrderUID != a.OrderUID {
+		t.Fatalf("misspelt item name: %#v", res)
+	}
+	if res := search("mozkin", app.PageRequest{}); len(res) != 1 || res[0].OrderUID != b.OrderUID {
+		t.Fatalf("delivery city: %#v", res)
+	}
+	if res := search("TRK-fts", app.PageRequest{}); len(res) != 2 {
+		t.Fatalf("track substring: %#v", res)
+	}
+
+	// Both orders mention Sabo, but only b also sells a lipstick.
+	res := search("sabo lipstick", app.PageRequest{SortBy: "relevance", SortDir: "desc"})
+	if len(res) == 0 || res[0].OrderUID != b.OrderUID {
+		t.Fatalf("relevance order: %#v", res)
+	}
+	q := "sabo lipstick"
+	var exported []string
+	if err := r.ExportOrders(ctx, app.SearchFilters{Query: &q}, "relevance", "desc", func(o order.Order) error {
+		exported = append(exported, o.OrderUID)
+		return nil
+	}); err != nil || len(exported) == 0 || exported[0] != b.OrderUID {
+		t.Fatalf("export relevance order: %v %v", exported, err)
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE order_items SET item_name = 'Eyeliner' WHERE order_uid = $1`, a.OrderUID); err != nil {
+		t.Fatalf("rename item: %v", err)
+	}
+	if res := search("eyeliner", app.PageRequest{}); len(res) != 1 || res[0].OrderUID != a.OrderUID {
+		t.Fatalf("document not refreshed after item rename: %#v", res)
+	}
+}
+
+func TestRepo_SearchOrders_ByItemDeliveryAndAmount(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	a := makeOrder("crit-a", true)
+	a.Delivery.City, a.Delivery.Region = "Moscow", "Moscow Oblast"
+	b := makeOrder("crit-b", false)
+	b.Delivery.City, b.DeliveryService = "Kazan", "meest"
+	b.Payment.Amount = 1000
+	c := makeOrder("crit-c", false)
+	c.Items[0].NmId, c.Items[0].Brand = "nm-9", "Brand-9"
+	c.Payment.Amount = 50
+	for _, o := range []order.Order{a, b, c} {
+		if _, err := r.CreateOrUpdateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", o.OrderUID, err)
+		}
+	}
+
+	uids := func(f app.SearchFilters) []string {
+		t.Helper()
+		res, err := searchOrders(ctx, r, f, app.PageRequest{Limit: 10, SortBy: "track_number", SortDir: "asc"})
+		if err != nil {
+			t.Fatalf("Search %+v: %v", f, err)
+		}
+		out := make([]string, 0, len(res))
+		for _, o := range res {
+			out = append(out, o.OrderUID)
+		}
+		return out
+	}
+	amount := func(v int64) *int64 { return &v }
+
+	cases := []struct {
+		name string
+		f    app.SearchFilters
+		want []string
+	}{
+		{"nm_id IN", app.SearchFilters{NmIDs: []string{"nm-2", "nm-9"}}, []string{"crit-a", "crit-c"}},
+		{"brand case-insensitive", app.SearchFilters{Brands: []string{"brand-9"}}, []string{"crit-c"}},
+		{"chrt_id", app.SearchFilters{ChrtIDs: []string{"ch2"}}, []string{"crit-a"}},
+		{"rid", app.SearchFilters{Rids: []string{"rid-1"}}, []string{"crit-a", "crit-b", "crit-c"}},
+		// nm-2 and brand-1 are on different items of crit-a.
+		{"item filters hold for one item", app.SearchFilters{NmIDs: []string{"nm-2"}, Brands: []string{"brand-1"}}, []string{}},
+		{"city IN", app.SearchFilters{Cities: []string{"moscow", "kazan"}}, []string{"crit-a", "crit-b"}},
+		{"region", app.SearchFilters{Regions: []string{"moscow oblast"}}, []string{"crit-a"}},
+		{"phone", app.SearchFilters{Phones: []string{"+123"}}, []string{"crit-a", "crit-b", "crit-c"}},
+		{"email", app.SearchFilters{Emails: []string{"john-crit-b@example.com"}}, []string{"crit-b"}},
+		{"delivery_service", app.SearchFilters{DeliveryServices: []string{"meest"}}, []string{"crit-b"}},
+		{"amount range", app.SearchFilters{AmountMin: amount(100), AmountMax: amount(300)}, []string{"crit-a"}},
+		{"amount min", app.SearchFilters{AmountMin: amount(301)}, []string{"crit-b"}},
+		{"combined", app.SearchFilters{Cities: []string{"moscow"}, AmountMax: amount(300), Brands: []string{"brand-2"}}, []string{"crit-a"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := uids(tc.f); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRepo_ExportOrders_StreamsMatchingOrdersInSortOrder(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	b := makeOrder("exp-b", true)
+	b.Payment.Currency = "EUR"
+	for _, o := range []order.Order{makeOrder("exp-c", false), b, makeOrder("exp-a", false)} {
+		if _, err := r.CreateOrUpdateOrder(ctx, o); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", o.OrderUID, err)
+		}
+	}
+
+	var got []string
+	err := r.ExportOrders(ctx, app.SearchFilters{}, "track_number", "asc", func(o order.Order) error {
+		got = append(got, fmt.Sprintf("%s/%d", o.OrderUID, len(o.Items)))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportOrders: %v", err)
+	}
+	if fmt.Sprint(got) != "[exp-a/1 exp-b/2 exp-c/1]" {
+		t.Fatalf("unexpected export: %v", got)
+	}
+
+	usd := "USD"
+	got = nil
+	err = r.ExportOrders(ctx, app.SearchFilters{Currency: &usd}, "track_number", "desc", func(o order.Order) error {
+		got = append(got, o.OrderUID)
+		return nil
+	})
+	if err != nil || fmt.Sprint(got) != "[exp-c exp-a]" {
+		t.Fatalf("filtered export: %v / %v", err, got)
+	}
+
+	stop := errors.New("client went away")
+	n := 0
+	err = r.ExportOrders(ctx, app.SearchFilters{}, "date_created", "desc", func(order.Order) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Fatalf("expected export to stop at the first error, got %v after %d orders", err, n)
+	}
+}
+
+func TestRepo_CreateOrUpdateOrders_PerRecordAndAtomic(t *testing.T) {
+	r, pool := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := r.CreateOrUpdateOrder(ctx, makeOrder("bulk-old", false)); err != nil {
+		t.Fatalf("CreateOrUpdateOrder: %v", err)
+	}
+	dup := makeOrder("bulk-dup", false)
+	dup.Payment.Transaction = "tx-bulk-old" // unique violation on payments.transaction
+	stale := makeOrder("bulk-old", true)
+	stale.Version = 7
+
+	event := func(uid string) []app.OutboxEvent {
+		return []app.OutboxEvent{{AggregateID: uid, Topic: "orders-events", Key: []byte(uid), EventType: "order.upserted", Payload: []byte(`{}`)}}
+	}
+	var recs []app.BulkRecord
+	for _, o := range []order.Order{makeOrder("bulk-a", true), dup, makeOrder("bulk-old", true), stale, makeOrder("bulk-b", false)} {
+		recs = append(recs, app.BulkRecord{Order: o, Events: event(o.OrderUID)})
+	}
+
+	res, err := r.CreateOrUpdateOrders(ctx, recs, app.BulkOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOrders: %v", err)
+	}
+	if res[0].Err != nil || !res[0].Created || len(res[0].Order.Items) != 2 {
+		t.Fatalf("bulk-a: %+v", res[0])
+	}
+	if !errors.Is(res[1].Err, app.ErrConflict) {
+		t.Fatalf("duplicate transaction must fail alone: %+v", res[1])
+	}
+	if res[2].Err != nil || res[2].Created || res[2].Order.Version != 2 {
+		t.Fatalf("bulk-old update: %+v", res[2])
+	}
+	if !errors.Is(res[3].Err, app.ErrConflict) {
+		t.Fatalf("stale version must conflict: %+v", res[3])
+	}
+	if res[4].Err != nil || !res[4].Created {
+		t.Fatalf("bulk-b: %+v", res[4])
+	}
+
+	var orders, events, revisions int
+	if err := pool.QueryRow(ctx, `SELECT
+		(SELECT count(*) FROM orders WHERE order_uid LIKE 'bulk-%'),
+		(SELECT count(*) FROM outbox WHERE aggregate_id LIKE 'bulk-%'),
+		(SELECT count(*) FROM order_revisions WHERE order_uid LIKE 'bulk-%')`).Scan(&orders, &events, &revisions); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	// bulk-old has the revision of its first write too.
+	if orders != 3 || events != 3 || revisions != 4 {
+		t.Fatalf("orders=%d events=%d revisions=%d", orders, events, revisions)
+	}
+
+	atomic := []app.BulkRecord{{Order: makeOrder("bulk-c", false)}, {Order: dup}}
+	res, err = r.CreateOrUpdateOrders(ctx, atomic, app.BulkOptions{BatchSize: 1, Atomic: true})
+	if err != nil {
+		t.Fatalf("CreateOrUpdateOrders atomic: %v", err)
+	}
+	if !errors.Is(res[0].Err, app.ErrBulkAborted) || !errors.Is(res[1].Err, app.ErrConflict) {
+		t.Fatalf("unexpected atomic results: %+v", res)
+	}
+	if _, err := r.GetOrder(ctx, "bulk-c"); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("bulk-c must be rolled back, got %v", err)
+	}
+}
+
+func TestRepo_IdempotencyKeys_ReserveCompleteAndExpire(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	s := repo.NewIdempotencyRepo(pool)
+
+	if _, reserved, err := s.Reserve(ctx, "key-1", "fp-1", time.Minute); err != nil || !reserved {
+		t.Fatalf("first Reserve: reserved=%v err=%v", reserved, err)
+	}
+	rec, reserved, err := s.Reserve(ctx, "key-1", "fp-1", time.Minute)
+	if err != nil || reserved || rec.Fingerprint != "fp-1" || rec.Response != nil {
+		t.Fatalf("in-flight Reserve: %+v reserved=%v err=%v", rec, reserved, err)
+	}
+
+	resp := app.IdempotentResponse{Status: 201, Header: map[string]string{"Location": "/orders/a"}, Body: []byte(`{"order_uid":"a"}`)}
+	if err := s.Complete(ctx, "key-1", resp, 24*time.Hour); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	var kept bool
+	if err := pool.QueryRow(ctx, `SELECT expires_at > now() + interval '23 hours' FROM idempotency_keys WHERE key = 'key-1'`).Scan(&kept); err != nil || !kept {
+		t.Fatalf("Complete must keep the response for the TTL: kept=%v err=%v", kept, err)
+	}
+	if err := s.Release(ctx, "key-1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	rec, reserved, err = s.Reserve(ctx, "key-1", "fp-2", time.Minute)
+	if err != nil || reserved || rec.Fingerprint != "fp-1" || rec.Response == nil {
+		t.Fatalf("completed key must be kept: %+v reserved=%v err=%v", rec, reserved, err)
+	}
+	if rec.Response.Status != 201 || rec.Response.Header["Location"] != "/orders/a" || string(rec.Response.Body) != `{"order_uid":"a"}` {
+		t.Fatalf("stored response: %+v", rec.Response)
+	}
+
+	if _, reserved, _ := s.Reserve(ctx, "key-2", "fp", time.Minute); !reserved {
+		t.Fatal("key-2 must be reserved")
+	}
+	if err := s.Release(ctx, "key-2"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if _, reserved, _ := s.Reserve(ctx, "key-2", "fp-other", time.Minute); !reserved {
+		t.Fatal("released key must be reservable again")
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE idempotency_keys SET expires_at = now() - interval '1 minute' WHERE key = 'key-1'`); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if _, reserved, _ := s.Reserve(ctx, "key-1", "fp-2", time.Minute); !reserved {
+		t.Fatal("expired key must be reservable again")
+	}
+
+	// A request that never finished holds its key only for the lock.
+	if _, reserved, _ := s.Reserve(ctx, "key-3", "fp", 10*time.Millisecond); !reserved {
+		t.Fatal("key-3 must be reserved")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, reserved, _ := s.Reserve(ctx, "key-3", "fp", time.Minute); !reserved {
+		t.Fatal("stale in-flight key must be taken over")
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE idempotency_keys SET expires_at = now() - interval '1 minute'`); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if n, err := s.PurgeExpired(ctx); err != nil || n != 3 {
+		t.Fatalf("PurgeExpired: n=%d err=%v", n, err)
+	}
+}
+
+func TestRepo_GetOrderByTrack_SkipsDeleted(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, uid := range []string{"trk-a", "trk-b"} {
+		if _, err := r.CreateOrUpdateOrder(ctx, makeOrder(uid, true)); err != nil {
+			t.Fatalf("CreateOrUpdateOrder %s: %v", uid, err)
+		}
+	}
+
+	got, err := r.GetOrderByTrack(ctx, "TRK-trk-b")
+	if err != nil {
+		t.Fatalf("GetOrderByTrack: %v", err)
+	}
+	if got.OrderUID != "trk-b" || len(got.Items) != 2 {
+		t.Fatalf("unexpected order: %+v", got)
+	}
+
+	if err := r.DeleteOrder(ctx, "trk-b"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	if _, err := r.GetOrderByTrack(ctx, "TRK-trk-b"); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("deleted order must not be tracked, got %v", err)
+	}
+	if _, err := r.GetOrderByTrack(ctx, "TRK-unknown"); !errors.Is(err, app.ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
+
+func insertOutboxEvent(t *testing.T, pool *pgxpool.Pool, aggregateID, eventType string) int64 {
+	t.Helper()
+	var id int64
+	if err := pool.QueryRow(context.Background(),
+		`INSERT INTO outbox (aggregate_id, topic, event_type, payload) VALUES ($1, 'orders-events', $2, '{}') RETURNING id`,
+		aggregateID, eventType).Scan(&id); err != nil {
+		t.Fatalf("insert outbox event: %v", err)
+	}
+	return id
+}
+
+func TestRepo_Outbox_ClaimsOldestEventPerAggregate(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	store := repo.NewOutboxRepo(pool)
+
+	x1 := insertOutboxEvent(t, pool, "x", "order.upserted")
+	x2 := insertOutboxEvent(t, pool, "x", "order.deleted")
+	y1 := insertOutboxEvent(t, pool, "y", "order.upserted")
+
+	var sent []int64
+	send := func(_ context.Context, rec app.OutboxRecord) error {
+		sent = append(sent, rec.ID)
+		return nil
+	}
+	res, err := store.DispatchPending(ctx, app.OutboxDispatchOptions{Limit: 10}, send)
+	if err != nil {
+		t.Fatalf("DispatchPending: %v", err)
+	}
+	if res.Sent != 2 || fmt.Sprint(sent) != fmt.Sprint([]int64{x1, y1}) {
+		t.Fatalf("first dispatch must send only the oldest event per order: sent=%v res=%+v", sent, res)
+	}
+
+	sent = nil
+	if _, err := store.DispatchPending(ctx, app.OutboxDispatchOptions{Limit: 10}, send); err != nil {
+		t.Fatalf("DispatchPending: %v", err)
+	}
+	if fmt.Sprint(sent) != fmt.Sprint([]int64{x2}) {
+		t.Fatalf("second dispatch: sent=%v", sent)
+	}
+}
+
+func TestRepo_Outbox_BackoffAndDeadLetterBlockOrder(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	store := repo.NewOutboxRepo(pool)
+
+	x1 := insertOutboxEvent(t, pool, "x", "order.upserted")
+	insertOutboxEvent(t, pool, "x", "order.deleted")
+
+	var sent []int64
+	fail := func(_ context.Context, rec app.OutboxRecord) error { return errors.New("broker down") }
+	ok := func(_ context.Context, rec app.OutboxRecord) error {
+		sent = append(sent, rec.ID)
+		return nil
+	}
+	opts := app.OutboxDispatchOptions{Limit: 10, MaxAttempts: 2, Backoff: time.Minute}
+
+	res, err := store.DispatchPending(ctx, opts, fail)
+	if err != nil || res.Failed != 1 {
+		t.Fatalf("DispatchPending: res=%+v err=%v", res, err)
+	}
+	var (
+		status   string
+		attempts int
+		waitSecs float64
+	)
+	if err := pool.QueryRow(ctx, `SELECT status, attempts, extract(epoch FROM next_attempt_at - now()) FROM outbox WHERE id = $1`, x1).
+		Scan(&status, &attempts, &waitSecs); err != nil {
+		t.Fatalf("read outbox: %v", err)
+	}
+	if status != "pending" || attempts != 1 || waitSecs < 50 {
+		t.Fatalf("failed event must back off: status=%s attempts=%d wait=%.0fs", status, attempts, waitSecs)
+	}
+
+	if _, err := store.DispatchPending(ctx, opts, ok); err != nil || len(sent) != 0 {
+		t.Fatalf("event in backoff and its successor must not be sent: sent=%v err=%v", sent, err)
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE outbox SET next_attempt_at = now() - interval '1 second' WHERE id = $1`, x1); err != nil {
+		t.Fatalf("expire backoff: %v", err)
+	}
+	res, err = store.DispatchPending(ctx, opts, fail)
+	if err != nil || res.Dead != 1 {
+		t.Fatalf("second failure must dead-letter: res=%+v err=%v", res, err)
+	}
+
+	if _, err := store.DispatchPending(ctx, opts, ok); err != nil || len(sent) != 0 {
+		t.Fatalf("a dead-lettered event must block the rest of its order: sent=%v err=%v", sent, err)
+	}
+}
+
+func TestRepo_Outbox_PublishesWithoutHoldingLocks(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	store := repo.NewOutboxRepo(pool)
+
+	x1 := insertOutboxEvent(t, pool, "x", "order.upserted")
+	res, err := store.DispatchPending(ctx, app.OutboxDispatchOptions{Limit: 10, Lease: time.Minute}, func(ctx context.Context, rec app.OutboxRecord) error {
+		// Would block on the row lock if the claim were still open.
+		lockCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		if _, err := pool.Exec(lockCtx, `UPDATE outbox SET last_error = NULL WHERE id = $1`, rec.ID); err != nil {
+			return err
+		}
+		// Another relay must not claim a leased event.
+		again, err := store.DispatchPending(ctx, app.OutboxDispatchOptions{Limit: 10}, func(context.Context, app.OutboxRecord) error {
+			return errors.New("leased event claimed twice")
+		})
+		if err != nil || again.Sent+again.Failed+again.Dead != 0 {
+			return fmt.Errorf("second claim: %+v, %v", again, err)
+		}
+		return nil
+	})
+	if err != nil || res.Sent != 1 {
+		t.Fatalf("DispatchPending: res=%+v err=%v", res, err)
+	}
+	var status string
+	if err := pool.QueryRow(ctx, `SELECT status FROM outbox WHERE id = $1`, x1).Scan(&status); err != nil || status != "sent" {
+		t.Fatalf("status=%s err=%v", status, err)
+	}
+}
+
+func TestRepo_Outbox_NoSendPastTheLease(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	store := repo.NewOutboxRepo(pool)
+
+	x1 := insertOutboxEvent(t, pool, "x", "order.upserted")
+	insertOutboxEvent(t, pool, "y", "order.upserted")
+	sends := 0
+	opts := app.OutboxDispatchOptions{Limit: 10, Lease: time.Second, SendTimeout: 600 * time.Millisecond}
+	res, err := store.DispatchPending(ctx, opts, func(context.Context, app.OutboxRecord) error {
+		sends++
+		// The second send could not finish within the lease.
+		time.Sleep(500 * time.Millisecond)
+		return nil
+	})
+	if err != nil || res.Sent != 1 || sends != 1 {
+		t.Fatalf("DispatchPending: res=%+v sends=%d err=%v", res, sends, err)
+	}
+
+	var status string
+	if err := pool.QueryRow(ctx, `SELECT status FROM outbox WHERE id <> $1`, x1).Scan(&status); err != nil || status != "pending" {
+		t.Fatalf("unsent event: status=%s err=%v", status, err)
+	}
+}
+
+func TestRepo_Outbox_PurgeSent(t *testing.T) {
+	_, pool := newTestRepo(t)
+	ctx := context.Background()
+	store := repo.NewOutboxRepo(pool)
+
+	old := insertOutboxEvent(t, pool, "a", "order.upserted")
+	recent := insertOutboxEvent(t, pool, "b", "order.upserted")
+	insertOutboxEvent(t, pool, "c", "order.upserted")
+	if _, err := pool.Exec(ctx, `
+		UPDATE outbox SET status = 'sent', sent_at = CASE WHEN id = $1 THEN now() - interval '2 days' ELSE now() END
+		WHERE id IN ($1, $2)`, old, recent); err != nil {
+		t.Fatalf("mark sent: %v", err)
+	}
+
+	n, err := store.PurgeSent(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeSent: n=%d err=%v", n, err)
+	}
+	var left int
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM outbox`).Scan(&left); err != nil || left != 2 {
+		t.Fatalf("left=%d err=%v", left, err)
+	}
+}
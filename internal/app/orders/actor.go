@@ -0,0 +1,23 @@
+package orders
+
+import "context"
+
+// Actor identifies who made a change; it is stored with order revisions.
+// Source is the channel the change came through, "http" or "kafka", and is
+// recorded in the events it produces.
+type Actor struct {
+	Name      string
+	RequestID string
+	Source    string
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+func ActorFromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
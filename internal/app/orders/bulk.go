@@ -0,0 +1,37 @@
+package orders
+
+import (
+	"context"
+
+	domain "github.com/reybrally/order-service/internal/domain/order"
+)
+
+// BulkOptions controls a bulk import. Records are written BatchSize at a
+// time; with Atomic all of them are committed together or not at all.
+type BulkOptions struct {
+	BatchSize int
+	Atomic    bool
+}
+
+// BulkRecord is one order of a bulk import with the events stored with it.
+type BulkRecord struct {
+	Order  domain.Order
+	Events []OutboxEvent
+}
+
+// BulkResult is the outcome of one record. Err is nil for a stored order;
+// Created tells an insert from an update.
+type BulkResult struct {
+	Order   domain.Order
+	Created bool
+	Err     error
+}
+
+// OrderBulkWriter stores many orders with the semantics of
+// OrderCreatorUpdater.CreateOrUpdateOrder for each. Results are in record
+// order. Without opts.Atomic a record the database rejects fails alone; an
+// error is returned only when the import could not go on, with the results of
+// records not stored set to it.
+type OrderBulkWriter interface {
+	CreateOrUpdateOrders(ctx context.Context, records []BulkRecord, opts BulkOptions) ([]BulkResult, error)
+}
@@ -12,4 +12,7 @@ var (
 	ErrConflict         = errors.New("conflict")
 	ErrRetryable        = errors.New("retryable")
 	ErrTimeout          = errors.New("timeout")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrNoExchangeRate   = errors.New("no exchange rate")
+	ErrBulkAborted      = errors.New("not stored: another record of the all-or-nothing import failed")
 )
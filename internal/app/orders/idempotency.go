@@ -0,0 +1,36 @@
+package orders
+
+import (
+	"context"
+	"time"
+)
+
+// IdempotentResponse is the response stored for an Idempotency-Key and replayed
+// to retries of the same request.
+type IdempotentResponse struct {
+	Status int
+	Header map[string]string
+	Body   []byte
+}
+
+// IdempotencyRecord is the state of a key: the fingerprint of the request that
+// first used it and, once that request has finished, its response. Response
+// is nil while the request is still in flight.
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string
+	Response    *IdempotentResponse
+}
+
+type IdempotencyStore interface {
+	// Reserve claims key for a request with fingerprint until lock passes, so
+	// a key left behind by a request that never finished is freed soon. If
+	// the key is already claimed and not expired, the existing record is
+	// returned with reserved == false.
+	Reserve(ctx context.Context, key, fingerprint string, lock time.Duration) (rec IdempotencyRecord, reserved bool, err error)
+	// Complete stores the response of a reserved key and keeps it for ttl.
+	Complete(ctx context.Context, key string, resp IdempotentResponse, ttl time.Duration) error
+	// Release drops a key whose request did not finish, so it can be retried.
+	Release(ctx context.Context, key string) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
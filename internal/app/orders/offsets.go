@@ -0,0 +1,29 @@
+package orders
+
+import "context"
+
+type ConsumerOffset struct {
+	Topic     string
+	Partition int
+	GroupID   string
+	Offset    int64
+}
+
+type OffsetStore interface {
+	LastOffset(ctx context.Context, topic string, partition int, groupID string) (int64, bool, error)
+	SaveOffset(ctx context.Context, off ConsumerOffset) error
+}
+
+type consumerOffsetKey struct{}
+
+// WithConsumerOffset marks ctx as handling a consumed message. Repositories
+// record the offset in the same transaction as the data they write, so a
+// redelivered message is recognised as already processed.
+func WithConsumerOffset(ctx context.Context, off ConsumerOffset) context.Context {
+	return context.WithValue(ctx, consumerOffsetKey{}, off)
+}
+
+func ConsumerOffsetFromContext(ctx context.Context) (ConsumerOffset, bool) {
+	off, ok := ctx.Value(consumerOffsetKey{}).(ConsumerOffset)
+	return off, ok
+}
@@ -0,0 +1,56 @@
+package orders
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+)
+
+// OutboxEvent is an event that must be written in the same transaction as the
+// order change it describes. AggregateID defines per-order delivery ordering.
+type OutboxEvent struct {
+	AggregateID string
+	Topic       string
+	Key         []byte
+	EventType   string
+	Payload     []byte
+	Headers     map[string]string
+}
+
+type OutboxRecord struct {
+	ID        int64
+	Attempts  int
+	CreatedAt time.Time
+	OutboxEvent
+}
+
+type OutboxDispatchOptions struct {
+	Limit       int
+	MaxAttempts int
+	Backoff     time.Duration
+	// Lease is how long claimed events are reserved for this dispatch; it must
+	// cover publishing Limit events.
+	Lease time.Duration
+	// SendTimeout bounds a single send. No send starts unless it would end
+	// within the lease, so a re-claimed event is never published concurrently
+	// with the events after it.
+	SendTimeout time.Duration
+}
+
+type OutboxDispatchResult struct {
+	Sent   int
+	Failed int
+	Dead   int
+}
+
+type OutboxSender func(ctx context.Context, rec OutboxRecord) error
+
+type OutboxStore interface {
+	DispatchPending(ctx context.Context, opts OutboxDispatchOptions, send OutboxSender) (OutboxDispatchResult, error)
+	PurgeSent(ctx context.Context, before time.Time) (int64, error)
+}
@@ -7,19 +7,46 @@ import (
 )
 
 type OrderCreatorUpdater interface {
-	CreateOrUpdateOrder(ctx context.Context, o domain.Order) (domain.Order, error)
+	CreateOrUpdateOrder(ctx context.Context, o domain.Order, events ...OutboxEvent) (domain.Order, error)
 }
 
 type OrderGetter interface {
 	GetOrder(ctx context.Context, id string) (domain.Order, error)
+	GetOrders(ctx context.Context, ids []string) ([]domain.Order, error)
+	GetOrderByTrack(ctx context.Context, trackNumber string) (domain.Order, error)
 }
 
 type OrderDeleter interface {
-	DeleteOrder(ctx context.Context, id string) error
+	DeleteOrder(ctx context.Context, id string, events ...OutboxEvent) error
+	RestoreOrder(ctx context.Context, id string, events ...OutboxEvent) (domain.Order, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 }
 
 type OrderSearcher interface {
-	SearchOrders(ctx context.Context, filters SearchFilters, request PageRequest) ([]domain.Order, error)
+	SearchOrders(ctx context.Context, filters SearchFilters, request PageRequest) (Page, error)
+}
+
+// OrderExporter streams every order matching the filters to fn, ordered by a
+// search sort column, and stops at the first error fn returns.
+type OrderExporter interface {
+	ExportOrders(ctx context.Context, filters SearchFilters, sortBy, sortDir string, fn func(domain.Order) error) error
+}
+
+type OrderStatusChanger interface {
+	ChangeStatus(ctx context.Context, ch domain.StatusChange, events ...OutboxEvent) error
+	StatusHistory(ctx context.Context, id string) ([]domain.StatusChange, error)
+}
+
+type OrderHistoryReader interface {
+	Revisions(ctx context.Context, id string) ([]domain.Revision, error)
+	Revision(ctx context.Context, id string, rev int64) (domain.Revision, error)
+}
+
+// OrderConsistencyReader finds stored orders breaking the invariants of
+// domain.Order.CheckConsistency. The cursor is the last order_uid of the
+// previous page.
+type OrderConsistencyReader interface {
+	InconsistentOrders(ctx context.Context, after string, limit int) (Page, error)
 }
 
 type SearchFilters struct {
@@ -33,18 +60,63 @@ type SearchFilters struct {
 	Currency    *string
 
 	Query *string
+
+	// Item filters match an order with at least one item meeting all of them.
+	// Each list is OR-ed: brand=a,b matches either brand.
+	NmIDs   []string
+	Brands  []string
+	ChrtIDs []string
+	Rids    []string
+
+	DeliveryServices []string
+	Cities           []string
+	Regions          []string
+	Phones           []string
+	Emails           []string
+
+	// AmountMin and AmountMax bound payment.amount, in minor units, inclusive.
+	AmountMin *int64
+	AmountMax *int64
+
+	IncludeDeleted bool
 }
 
+// PageRequest selects a page either by Offset or, preferably, by Cursor, the
+// opaque NextCursor of the previous page. Cursor takes precedence over Offset.
 type PageRequest struct {
 	Limit   int
 	Offset  int
 	SortBy  string
 	SortDir string
+	Cursor  string
+}
+
+// Page is one page of search results. NextCursor is empty on the last page.
+type Page struct {
+	Orders     []domain.Order
+	NextCursor string
 }
 
 type OrderRepo interface {
 	OrderCreatorUpdater
+	OrderBulkWriter
 	OrderGetter
 	OrderDeleter
 	OrderSearcher
+	OrderExporter
+	OrderStatusChanger
+	OrderHistoryReader
+	OrderConsistencyReader
+	OrderStatsReader
+}
+
+// InconsistentOrder is a stored order with the invariants it breaks.
+type InconsistentOrder struct {
+	OrderUID string
+	Issues   []domain.Inconsistency
+}
+
+type ConsistencyReport struct {
+	Orders     []InconsistentOrder
+	NextCursor string
 }
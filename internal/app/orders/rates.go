@@ -0,0 +1,29 @@
+package orders
+
+import (
+	"context"
+	"time"
+
+	"github.com/reybrally/order-service/internal/domain/money"
+)
+
+// RateRequest asks for the rate of Base in effect at At.
+type RateRequest struct {
+	Base string
+	At   time.Time
+}
+
+type ExchangeRates interface {
+	// RatesAt returns one rate into quote per request, in request order. The
+	// Value of a rate is nil when the pair had no rate in effect at that time.
+	RatesAt(ctx context.Context, quote string, reqs []RateRequest) ([]money.Rate, error)
+	SaveRates(ctx context.Context, rates []money.Rate) error
+}
+
+// Conversion is the payment amount of an order in a reporting currency. Rate
+// has a nil Value for an order without a payment.
+type Conversion struct {
+	OrderUID string
+	Amount   money.Money
+	Rate     money.Rate
+}
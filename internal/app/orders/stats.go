@@ -0,0 +1,69 @@
+package orders
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/reybrally/order-service/internal/domain/money"
+)
+
+// Dimensions orders can be grouped by in OrderStats.
+const (
+	StatsByDay             = "day"
+	StatsByProvider        = "provider"
+	StatsByCurrency        = "currency"
+	StatsByDeliveryService = "delivery_service"
+	StatsByBrand           = "brand"
+	StatsByRegion          = "region"
+)
+
+var StatsDimensions = []string{
+	StatsByDay, StatsByProvider, StatsByCurrency, StatsByDeliveryService, StatsByBrand, StatsByRegion,
+}
+
+func ValidStatsDimension(s string) bool {
+	for _, d := range StatsDimensions {
+		if d == s {
+			return true
+		}
+	}
+	return false
+}
+
+type StatsRequest struct {
+	GroupBy string
+	// ReportingCurrency converts revenue with the rate in effect at
+	// payment_dt. When empty, every group is split by payment currency.
+	ReportingCurrency string
+}
+
+// StatsRow is one SQL aggregate: the orders of one dimension key paid in one
+// currency. Revenue is the sum of payment amounts, or of item totals when
+// grouping by brand. Rated is the sum of amount*rate into the reporting
+// currency and MissingRates the number of orders that had no rate.
+type StatsRow struct {
+	Key          string
+	Currency     string
+	Orders       int64
+	Items        int64
+	Revenue      int64
+	Rated        *big.Rat
+	MissingRates int64
+}
+
+type OrderStatsReader interface {
+	OrderStats(ctx context.Context, f SearchFilters, req StatsRequest) ([]StatsRow, error)
+}
+
+type StatsGroup struct {
+	Key           string
+	Orders        int64
+	Items         int64
+	Revenue       money.Money
+	AvgOrderValue money.Money
+}
+
+type Stats struct {
+	GroupBy string
+	Groups  []StatsGroup
+}
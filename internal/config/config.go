@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -10,6 +11,12 @@ import (
 type App struct {
 	Env          string
 	CacheBackend string
+	// ConsistencyMode is "strict" (reject orders whose totals do not add up)
+	// or "warn" (store them and log a warning).
+	ConsistencyMode string
+	// EmailValidation is "strict" (delivery.email must be a valid address) or
+	// "lenient" (any non-empty value, as before the check was added).
+	EmailValidation string
 }
 
 type HTTP struct {
@@ -17,6 +24,8 @@ type HTTP struct {
 }
 
 type DB struct {
+	// URL is DATABASE_URL; when set it takes precedence over the fields below.
+	URL      string
 	Host     string
 	Port     string
 	Name     string
@@ -25,11 +34,29 @@ type DB struct {
 	SSLMode  string
 }
 
+// DSN returns the connection string for pgxpool: URL if set, otherwise one
+// built from the individual fields.
+func (d DB) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     d.Host + ":" + d.Port,
+		Path:     "/" + d.Name,
+		RawQuery: "sslmode=" + url.QueryEscape(d.SSLMode),
+	}
+	return u.String()
+}
+
 type Kafka struct {
-	Brokers []string
-	Topic   string
-	Group   string
-	DLQ     string
+	Brokers     []string
+	Topic       string
+	Group       string
+	DLQ         string
+	IngestTopic string
+	IngestGroup string
 }
 
 type Redis struct {
@@ -40,12 +67,61 @@ type Redis struct {
 	Prefix   string
 }
 
+type Cache struct {
+	Capacity      int
+	WarmupSize    int
+	WarmupBatch   int
+	WarmupTimeout time.Duration
+}
+
+type Outbox struct {
+	PollInterval  time.Duration
+	BatchSize     int
+	MaxAttempts   int
+	Backoff       time.Duration
+	SentRetention time.Duration
+	// SendTimeout bounds one publish; Lease is how long a claimed batch is
+	// reserved for the relay and is raised to at least twice SendTimeout.
+	SendTimeout time.Duration
+	Lease       time.Duration
+}
+
+// Bulk limits POST /orders/bulk: records are written BatchSize at a time, and
+// a request may carry at most MaxRecords records and MaxBytes bytes and take
+// Timeout.
+type Bulk struct {
+	BatchSize  int
+	MaxRecords int
+	MaxBytes   int64
+	Timeout    time.Duration
+}
+
+// Idempotency controls Idempotency-Key support of POST /orders: a key and its
+// stored response live for TTL, a key whose request has not finished is held
+// for Lock, and expired keys are purged every PurgeInterval.
+type Idempotency struct {
+	TTL           time.Duration
+	Lock          time.Duration
+	PurgeInterval time.Duration
+}
+
+type Retention struct {
+	DeletedOrders time.Duration
+	PurgeInterval time.Duration
+}
+
 type Config struct {
-	App   App
-	HTTP  HTTP
-	DB    DB
-	Kafka Kafka
-	Redis Redis
+	App    App
+	HTTP   HTTP
+	DB     DB
+	Kafka  Kafka
+	Redis  Redis
+	Cache  Cache
+	Outbox Outbox
+	Bulk   Bulk
+
+	Idempotency Idempotency
+	Retention   Retention
 }
 
 func Load() Config {
@@ -53,11 +129,15 @@ func Load() Config {
 		App: App{
 			Env:          getenv("APP_ENV", "dev"),
 			CacheBackend: getenv("CACHE_BACKEND", "lru"),
+
+			ConsistencyMode: getenv("ORDER_CONSISTENCY_MODE", "strict"),
+			EmailValidation: getenv("ORDER_EMAIL_VALIDATION", "strict"),
 		},
 		HTTP: HTTP{
 			Port: getenv("PORT", "8080"),
 		},
 		DB: DB{
+			URL:      os.Getenv("DATABASE_URL"),
 			Host:     getenv("DB_HOST", "127.0.0.1"),
 			Port:     getenv("DB_PORT", "55432"),
 			Name:     getenv("DB_NAME", "orders_db"),
@@ -70,6 +150,10 @@ func Load() Config {
 			Topic:   getenv("ORDERS_EVENTS_TOPIC", "orders-events"),
 			Group:   getenv("ORDERS_CONSUMER_GROUP", "order-service-cache-projector"),
 			DLQ:     getenv("ORDERS_DLQ_TOPIC", "orders-events-dlq"),
+
+			// Ingestion from Kafka is off unless a topic is set.
+			IngestTopic: getenv("ORDERS_INGEST_TOPIC", ""),
+			IngestGroup: getenv("ORDERS_INGEST_GROUP", "order-service-ingest"),
 		},
 		Redis: Redis{
 			Addr:     getenv("REDIS_ADDR", "localhost:6379"),
@@ -78,6 +162,36 @@ func Load() Config {
 			TTL:      parseDuration(getenv("REDIS_TTL", "10m")),
 			Prefix:   getenv("REDIS_PREFIX", "order:"),
 		},
+		Cache: Cache{
+			Capacity:      atoi(getenv("CACHE_CAPACITY", "1000")),
+			WarmupSize:    atoi(getenv("CACHE_WARMUP_SIZE", "1000")),
+			WarmupBatch:   atoi(getenv("CACHE_WARMUP_BATCH", "100")),
+			WarmupTimeout: parseDuration(getenv("CACHE_WARMUP_TIMEOUT", "30s")),
+		},
+		Outbox: Outbox{
+			PollInterval:  parseDuration(getenv("OUTBOX_POLL_INTERVAL", "500ms")),
+			BatchSize:     atoi(getenv("OUTBOX_BATCH_SIZE", "100")),
+			MaxAttempts:   atoi(getenv("OUTBOX_MAX_ATTEMPTS", "10")),
+			Backoff:       parseDuration(getenv("OUTBOX_BACKOFF", "1s")),
+			SentRetention: parseDuration(getenv("OUTBOX_SENT_RETENTION", "24h")),
+			SendTimeout:   parseDuration(getenv("OUTBOX_SEND_TIMEOUT", "5s")),
+			Lease:         parseDuration(getenv("OUTBOX_LEASE", "30s")),
+		},
+		Bulk: Bulk{
+			BatchSize:  atoi(getenv("ORDERS_BULK_BATCH_SIZE", "100")),
+			MaxRecords: atoi(getenv("ORDERS_BULK_MAX_RECORDS", "10000")),
+			MaxBytes:   int64(atoi(getenv("ORDERS_BULK_MAX_BYTES", "67108864"))),
+			Timeout:    parseDuration(getenv("ORDERS_BULK_TIMEOUT", "2m")),
+		},
+		Idempotency: Idempotency{
+			TTL:           parseDuration(getenv("IDEMPOTENCY_KEY_TTL", "24h")),
+			Lock:          parseDuration(getenv("IDEMPOTENCY_LOCK_TIMEOUT", "30s")),
+			PurgeInterval: parseDuration(getenv("IDEMPOTENCY_PURGE_INTERVAL", "1h")),
+		},
+		Retention: Retention{
+			DeletedOrders: parseDuration(getenv("DELETED_ORDERS_RETENTION", "720h")),
+			PurgeInterval: parseDuration(getenv("DELETED_ORDERS_PURGE_INTERVAL", "1h")),
+		},
 	}
 }
 
@@ -0,0 +1,51 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in the minor units of its currency, e.g. 1050 USD is
+// $10.50.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func New(amount int64, code string) (Money, error) {
+	c, err := LookupCurrency(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: c}, nil
+}
+
+// Decimal formats the amount in major units with exactly Exponent fraction
+// digits: "10.50" for 1050 USD, "1050" for 1050 JPY.
+func (m Money) Decimal() string {
+	neg := m.Amount < 0
+	abs := uint64(m.Amount)
+	if neg {
+		abs = -abs
+	}
+	digits := strconv.FormatUint(abs, 10)
+
+	exp := m.Currency.Exponent
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	if exp == 0 {
+		b.WriteString(digits)
+		return b.String()
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	b.WriteString(digits[:len(digits)-exp])
+	b.WriteByte('.')
+	b.WriteString(digits[len(digits)-exp:])
+	return b.String()
+}
+
+func (m Money) String() string { return m.Decimal() + " " + m.Currency.Code }
@@ -0,0 +1,68 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	tests := []struct {
+		code string
+		exp  int
+	}{
+		{"USD", 2},
+		{"RUB", 2},
+		{"JPY", 0},
+		{"KWD", 3},
+		{"CLF", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			c, err := LookupCurrency(tt.code)
+			require.NoError(t, err)
+			assert.Equal(t, Currency{Code: tt.code, Exponent: tt.exp}, c)
+		})
+	}
+
+	for _, code := range []string{"", "usd", "XYZ", "US", "XAU"} {
+		_, err := LookupCurrency(code)
+		assert.True(t, errors.Is(err, ErrUnknownCurrency), code)
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		amount int64
+		code   string
+		want   string
+	}{
+		{1050, "USD", "10.50"},
+		{5, "USD", "0.05"},
+		{0, "USD", "0.00"},
+		{-1999, "EUR", "-19.99"},
+		{-7, "EUR", "-0.07"},
+		{1050, "JPY", "1050"},
+		{-3, "JPY", "-3"},
+		{1234567, "KWD", "1234.567"},
+		{42, "BHD", "0.042"},
+		{1, "CLF", "0.0001"},
+		{math.MinInt64, "USD", "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want+" "+tt.code, func(t *testing.T) {
+			m, err := New(tt.amount, tt.code)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Decimal())
+			assert.Equal(t, tt.want+" "+tt.code, m.String())
+		})
+	}
+}
+
+func TestNewUnknownCurrency(t *testing.T) {
+	_, err := New(100, "ABC")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
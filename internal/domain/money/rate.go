@@ -0,0 +1,93 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// Rate is the price of one major unit of Base in Quote. It is in effect from
+// ValidFrom until the next rate of the same pair.
+type Rate struct {
+	Base      string
+	Quote     string
+	Value     *big.Rat
+	ValidFrom time.Time
+}
+
+// ParseRate builds a rate from a decimal string such as "92.4512".
+func ParseRate(base, quote, value string, validFrom time.Time) (Rate, error) {
+	if _, err := LookupCurrency(base); err != nil {
+		return Rate{}, err
+	}
+	if _, err := LookupCurrency(quote); err != nil {
+		return Rate{}, err
+	}
+	v, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok || v.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("%w: %q", ErrInvalidRate, value)
+	}
+	return Rate{Base: base, Quote: quote, Value: v, ValidFrom: validFrom}, nil
+}
+
+// Identity is the rate of a currency to itself.
+func Identity(code string) Rate {
+	return Rate{Base: code, Quote: code, Value: big.NewRat(1, 1)}
+}
+
+// Decimal formats the rate with up to 12 fraction digits and no trailing zeros.
+func (r Rate) Decimal() string {
+	s := r.Value.FloatString(12)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// Convert converts m into r.Quote. The result is rounded half away from zero
+// to the minor unit of the quote currency.
+func (m Money) Convert(r Rate) (Money, error) {
+	if m.Currency.Code != r.Base {
+		return Money{}, fmt.Errorf("%w: rate %s/%s applied to %s", ErrInvalidRate, r.Base, r.Quote, m.Currency.Code)
+	}
+	to, err := LookupCurrency(r.Quote)
+	if err != nil {
+		return Money{}, err
+	}
+
+	v := new(big.Rat).SetInt64(m.Amount)
+	return ConvertRated(v.Mul(v, r.Value), m.Currency, to)
+}
+
+// ConvertRated turns minor units of from, already multiplied by a from/to
+// rate, into minor units of to. It lets a sum of amount*rate computed
+// elsewhere (e.g. in SQL) be rounded once, half away from zero.
+func ConvertRated(v *big.Rat, from, to Currency) (Money, error) {
+	v = new(big.Rat).Set(v)
+	if shift := to.Exponent - from.Exponent; shift != 0 {
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+		if shift > 0 {
+			v.Mul(v, scale)
+		} else {
+			v.Quo(v, scale)
+		}
+	}
+
+	q, rem := new(big.Int).QuoRem(v.Num(), v.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Abs(new(big.Int).Lsh(rem, 1)).Cmp(v.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(v.Sign())))
+	}
+	if !q.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s converted to %s overflows", ErrInvalidRate, from.Code, to.Code)
+	}
+	return Money{Amount: q.Int64(), Currency: to}, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,68 @@
+package money
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	r, err := ParseRate("USD", "RUB", " 92.450000 ", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "92.45", r.Decimal())
+
+	for _, bad := range []struct{ base, quote, value string }{
+		{"USD", "RUB", "0"},
+		{"USD", "RUB", "-1"},
+		{"USD", "RUB", "abc"},
+		{"USX", "RUB", "1"},
+		{"USD", "rub", "1"},
+	} {
+		_, err := ParseRate(bad.base, bad.quote, bad.value, time.Time{})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     int64
+		from, to   string
+		rate       string
+		wantAmount int64
+	}{
+		{"same exponent", 1050, "USD", "EUR", "0.9", 945},
+		{"rounds half up", 1, "USD", "EUR", "0.5", 1},
+		{"rounds half away from zero", -1, "USD", "EUR", "0.5", -1},
+		{"rounds down below half", 1, "USD", "EUR", "0.49", 0},
+		{"to zero exponent", 1050, "USD", "JPY", "150.25", 1578},
+		{"from zero exponent", 1000, "JPY", "USD", "0.0066", 660},
+		{"to three digits", 1000, "EUR", "KWD", "0.33", 3300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.amount, tt.from)
+			require.NoError(t, err)
+			r, err := ParseRate(tt.from, tt.to, tt.rate, time.Time{})
+			require.NoError(t, err)
+
+			got, err := m.Convert(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAmount, got.Amount)
+			assert.Equal(t, tt.to, got.Currency.Code)
+		})
+	}
+}
+
+func TestConvertIdentityAndMismatch(t *testing.T) {
+	m, _ := New(12345, "KWD")
+	got, err := m.Convert(Identity("KWD"))
+	require.NoError(t, err)
+	assert.Equal(t, m, got)
+
+	_, err = m.Convert(Identity("USD"))
+	assert.True(t, errors.Is(err, ErrInvalidRate))
+}
@@ -0,0 +1,101 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConsistencyMode decides what happens to an order whose totals do not add up:
+// ConsistencyStrict rejects it, ConsistencyWarn stores it and logs the issues.
+type ConsistencyMode string
+
+const (
+	ConsistencyStrict ConsistencyMode = "strict"
+	ConsistencyWarn   ConsistencyMode = "warn"
+)
+
+func ParseConsistencyMode(s string) (ConsistencyMode, error) {
+	switch m := ConsistencyMode(strings.ToLower(s)); m {
+	case ConsistencyStrict, ConsistencyWarn:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown consistency mode %q", s)
+}
+
+const (
+	CodeItemTotalMismatch  = "item_total_mismatch"
+	CodeGoodsTotalMismatch = "goods_total_mismatch"
+	CodeAmountMismatch     = "amount_mismatch"
+)
+
+var ErrInconsistent = errors.New("order totals are inconsistent")
+
+// Inconsistency is a broken financial invariant. Path is the JSON path of the
+// field holding the unexpected value.
+type Inconsistency struct {
+	Path     string `json:"path"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Expected int64  `json:"expected"`
+	Actual   int64  `json:"actual"`
+}
+
+// InconsistencyError carries every broken invariant of an order and matches
+// ErrInconsistent.
+type InconsistencyError []Inconsistency
+
+func (e InconsistencyError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, in := range e {
+		msgs = append(msgs, in.Path+": "+in.Message)
+	}
+	return ErrInconsistent.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e InconsistencyError) Is(target error) bool { return target == ErrInconsistent }
+
+// CheckConsistency verifies that every item costs price minus sale, that
+// goods_total is the sum of item totals and that amount is goods_total plus
+// delivery_cost and custom_fee. Payment invariants are skipped for an order
+// without a payment.
+func (o Order) CheckConsistency() []Inconsistency {
+	var out []Inconsistency
+	var goods int64
+	for i, it := range o.Items {
+		goods += it.TotalPrice
+		if want := it.Price - it.Sale; it.TotalPrice != want {
+			out = append(out, Inconsistency{
+				Path:     fmt.Sprintf("items[%d].total_price", i),
+				Code:     CodeItemTotalMismatch,
+				Message:  fmt.Sprintf("total price %d must equal price minus sale (%d)", it.TotalPrice, want),
+				Expected: want,
+				Actual:   it.TotalPrice,
+			})
+		}
+	}
+
+	p := o.Payment
+	if p.Transaction == "" {
+		return out
+	}
+	if p.GoodsTotal != goods {
+		out = append(out, Inconsistency{
+			Path:     "payment.goods_total",
+			Code:     CodeGoodsTotalMismatch,
+			Message:  fmt.Sprintf("goods total %d must equal the sum of item totals (%d)", p.GoodsTotal, goods),
+			Expected: goods,
+			Actual:   p.GoodsTotal,
+		})
+	}
+	if want := p.GoodsTotal + p.DeliveryCost + p.CustomFee; p.Amount != want {
+		out = append(out, Inconsistency{
+			Path:     "payment.amount",
+			Code:     CodeAmountMismatch,
+			Message:  fmt.Sprintf("amount %d must equal goods total plus delivery cost and custom fee (%d)", p.Amount, want),
+			Expected: want,
+			Actual:   p.Amount,
+		})
+	}
+	return out
+}
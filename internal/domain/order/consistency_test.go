@@ -0,0 +1,85 @@
+package order
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func consistentOrder() Order {
+	return Order{
+		OrderUID: "o-1",
+		Payment: Payment{
+			Transaction:  "tx-1",
+			Amount:       1170,
+			DeliveryCost: 150,
+			GoodsTotal:   1000,
+			CustomFee:    20,
+		},
+		Items: []Item{
+			{ChrtId: "1", Price: 500, Sale: 100, TotalPrice: 400},
+			{ChrtId: "2", Price: 600, Sale: 0, TotalPrice: 600},
+		},
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(o *Order)
+		want   []Inconsistency
+	}{
+		{"consistent", func(o *Order) {}, nil},
+		{"item total", func(o *Order) { o.Items[1].Sale = 50 }, []Inconsistency{
+			{Path: "items[1].total_price", Code: CodeItemTotalMismatch, Expected: 550, Actual: 600},
+		}},
+		{"goods total", func(o *Order) { o.Payment.GoodsTotal = 900; o.Payment.Amount = 1070 }, []Inconsistency{
+			{Path: "payment.goods_total", Code: CodeGoodsTotalMismatch, Expected: 1000, Actual: 900},
+		}},
+		{"amount", func(o *Order) { o.Payment.Amount = 1000 }, []Inconsistency{
+			{Path: "payment.amount", Code: CodeAmountMismatch, Expected: 1170, Actual: 1000},
+		}},
+		{"all at once", func(o *Order) { o.Items[0].TotalPrice = 450; o.Payment.Amount = 1 }, []Inconsistency{
+			{Path: "items[0].total_price", Code: CodeItemTotalMismatch, Expected: 400, Actual: 450},
+			{Path: "payment.goods_total", Code: CodeGoodsTotalMismatch, Expected: 1050, Actual: 1000},
+			{Path: "payment.amount", Code: CodeAmountMismatch, Expected: 1170, Actual: 1},
+		}},
+		{"no payment", func(o *Order) { o.Payment = Payment{} }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := consistentOrder()
+			tt.mutate(&o)
+
+			got := o.CheckConsistency()
+			require.Len(t, got, len(tt.want))
+			for i := range got {
+				assert.NotEmpty(t, got[i].Message)
+				got[i].Message = ""
+			}
+			if len(tt.want) > 0 {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInconsistencyErrorIs(t *testing.T) {
+	o := consistentOrder()
+	o.Payment.Amount = 0
+
+	err := error(InconsistencyError(o.CheckConsistency()))
+	assert.True(t, errors.Is(err, ErrInconsistent))
+	assert.Contains(t, err.Error(), "payment.amount")
+}
+
+func TestParseConsistencyMode(t *testing.T) {
+	m, err := ParseConsistencyMode("WARN")
+	require.NoError(t, err)
+	assert.Equal(t, ConsistencyWarn, m)
+
+	_, err = ParseConsistencyMode("lenient")
+	assert.Error(t, err)
+}
@@ -1,22 +1,37 @@
 package order
 
-import "time"
+import (
+	"time"
 
+	"github.com/reybrally/order-service/internal/domain/money"
+)
+
+// AnyVersion as the expected Version makes a write conditional on the order
+// existing, whatever its version (If-Match: *).
+const AnyVersion int64 = -1
+
+// Order is the order aggregate. When passed to a write, a positive Version is
+// the version the caller expects to be current, AnyVersion only requires the
+// order to exist and zero means an unconditional write.
+// DeletedAt is set while the order is soft-deleted.
 type Order struct {
-	OrderUID          string    `json:"order_uid"`
-	TrackNumber       string    `json:"track_number"`
-	Entry             string    `json:"entry"`
-	Locale            string    `json:"locale"`
-	InternalSignature string    `json:"internal_signature"`
-	CustomerId        string    `json:"customer_id"`
-	DeliveryService   string    `json:"delivery_service"`
-	ShardKey          string    `json:"shard_key"`
-	SmId              int64     `json:"sm_id"`
-	DateCreated       time.Time `json:"date_created"`
-	OofShard          int64     `json:"oof_shard"`
-	Delivery          Delivery  `json:"delivery"`
-	Payment           Payment   `json:"payment"`
-	Items             []Item    `json:"items"`
+	OrderUID          string     `json:"order_uid"`
+	TrackNumber       string     `json:"track_number"`
+	Entry             string     `json:"entry"`
+	Locale            string     `json:"locale"`
+	InternalSignature string     `json:"internal_signature"`
+	CustomerId        string     `json:"customer_id"`
+	DeliveryService   string     `json:"delivery_service"`
+	ShardKey          string     `json:"shard_key"`
+	SmId              int64      `json:"sm_id"`
+	DateCreated       time.Time  `json:"date_created"`
+	OofShard          int64      `json:"oof_shard"`
+	Status            Status     `json:"status"`
+	Version           int64      `json:"version"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	Delivery          Delivery   `json:"delivery"`
+	Payment           Payment    `json:"payment"`
+	Items             []Item     `json:"items"`
 }
 
 type Payment struct {
@@ -31,3 +46,9 @@ type Payment struct {
 	GoodsTotal   int64     `json:"goods_total"`
 	CustomFee    int64     `json:"custom_fee"`
 }
+
+// Money attaches the payment currency to an amount in its minor units, e.g.
+// p.Money(p.Amount). Item prices are in the payment currency as well.
+func (p Payment) Money(minor int64) (money.Money, error) {
+	return money.New(minor, p.Currency)
+}
@@ -0,0 +1,25 @@
+package order
+
+import "time"
+
+type RevisionOp string
+
+const (
+	RevisionCreate       RevisionOp = "create"
+	RevisionUpdate       RevisionOp = "update"
+	RevisionDelete       RevisionOp = "delete"
+	RevisionRestore      RevisionOp = "restore"
+	RevisionStatusChange RevisionOp = "status_change"
+)
+
+// Revision is a full snapshot of an order taken when it was changed.
+// Snapshot is empty when revisions are listed without their content.
+type Revision struct {
+	OrderUID  string     `json:"order_uid"`
+	Rev       int64      `json:"rev"`
+	Op        RevisionOp `json:"op"`
+	Actor     string     `json:"actor"`
+	RequestID string     `json:"request_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Snapshot  *Order     `json:"snapshot,omitempty"`
+}
@@ -0,0 +1,84 @@
+package order
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusCreated    Status = "created"
+	StatusPaid       Status = "paid"
+	StatusAssembling Status = "assembling"
+	StatusShipped    Status = "shipped"
+	StatusDelivered  Status = "delivered"
+	StatusCancelled  Status = "cancelled"
+	StatusReturned   Status = "returned"
+)
+
+var (
+	ErrUnknownStatus     = errors.New("unknown order status")
+	ErrInvalidTransition = errors.New("invalid status transition")
+)
+
+var transitions = map[Status][]Status{
+	StatusCreated:    {StatusPaid, StatusCancelled},
+	StatusPaid:       {StatusAssembling, StatusCancelled},
+	StatusAssembling: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered, StatusReturned},
+	StatusDelivered:  {StatusReturned},
+	StatusCancelled:  {},
+	StatusReturned:   {},
+}
+
+type StatusChange struct {
+	OrderUID  string    `json:"order_uid"`
+	From      Status    `json:"from"`
+	To        Status    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+func ParseStatus(s string) (Status, error) {
+	st := Status(s)
+	if !st.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrUnknownStatus, s)
+	}
+	return st, nil
+}
+
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+func (s Status) Final() bool {
+	return s.Valid() && len(transitions[s]) == 0
+}
+
+func (s Status) CanTransitionTo(to Status) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition validates moving from the current status to to and returns the
+// resulting change; the order itself is not modified.
+func (o Order) Transition(to Status, reason string, at time.Time) (StatusChange, error) {
+	from := o.Status
+	if from == "" {
+		from = StatusCreated
+	}
+	if !to.Valid() {
+		return StatusChange{}, fmt.Errorf("%w: %q", ErrUnknownStatus, to)
+	}
+	if !from.CanTransitionTo(to) {
+		return StatusChange{}, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+	return StatusChange{OrderUID: o.OrderUID, From: from, To: to, Reason: reason, ChangedAt: at}, nil
+}
@@ -0,0 +1,59 @@
+package order
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{StatusCreated, StatusPaid, true},
+		{StatusCreated, StatusCancelled, true},
+		{StatusCreated, StatusShipped, false},
+		{StatusPaid, StatusAssembling, true},
+		{StatusAssembling, StatusShipped, true},
+		{StatusShipped, StatusDelivered, true},
+		{StatusShipped, StatusCancelled, false},
+		{StatusDelivered, StatusReturned, true},
+		{StatusCancelled, StatusPaid, false},
+		{StatusReturned, StatusDelivered, false},
+		{StatusPaid, StatusPaid, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.ok, tt.from.CanTransitionTo(tt.to))
+		})
+	}
+}
+
+func TestOrderTransition(t *testing.T) {
+	at := time.Date(2025, 11, 1, 10, 0, 0, 0, time.UTC)
+
+	ch, err := Order{OrderUID: "a"}.Transition(StatusPaid, "payment captured", at)
+	require.NoError(t, err)
+	assert.Equal(t, StatusChange{OrderUID: "a", From: StatusCreated, To: StatusPaid, Reason: "payment captured", ChangedAt: at}, ch)
+
+	_, err = Order{OrderUID: "a", Status: StatusDelivered}.Transition(StatusCancelled, "", at)
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+
+	_, err = Order{OrderUID: "a"}.Transition(Status("lost"), "", at)
+	assert.True(t, errors.Is(err, ErrUnknownStatus))
+}
+
+func TestParseStatus(t *testing.T) {
+	st, err := ParseStatus("shipped")
+	require.NoError(t, err)
+	assert.Equal(t, StatusShipped, st)
+	assert.True(t, StatusCancelled.Final())
+	assert.False(t, StatusPaid.Final())
+
+	_, err = ParseStatus("SHIPPED")
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"errors"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"net/mail"
+	"sync/atomic"
+)
+
+// skipEmailFormat turns off the format check of delivery.email; see
+// SetEmailFormatCheck.
+var skipEmailFormat atomic.Bool
+
+// SetEmailFormatCheck turns the RFC 5322 format check of delivery.email on
+// (the default) or off. Before the check was introduced any non-empty address
+// was accepted; turning it off restores that for clients that relied on it.
+func SetEmailFormatCheck(on bool) {
+	skipEmailFormat.Store(!on)
+}
+
+func validateDelivery(vs *Violations, delivery domain.Delivery) {
+	if delivery.Name == "" {
+		vs.add("delivery.name", CodeRequired, "delivery name is required")
+	}
+	if delivery.Email == "" {
+		vs.add("delivery.email", CodeRequired, "mail is required")
+	} else if err := IsValidMail(delivery.Email); err != nil && !skipEmailFormat.Load() {
+		vs.add("delivery.email", CodeInvalid, err.Error())
+	}
+	if delivery.Phone == "" {
+		vs.add("delivery.phone", CodeRequired, "delivery phone is required")
+	}
+	if delivery.Address == "" {
+		vs.add("delivery.address", CodeRequired, "delivery address is required")
+	}
+	if delivery.City == "" {
+		vs.add("delivery.city", CodeRequired, "delivery city is required")
+	}
+	if delivery.Region == "" {
+		vs.add("delivery.region", CodeRequired, "delivery region is required")
+	}
+}
+
+func IsValidMail(mai string) error {
+	if mai == "" {
+		return errors.New("mail is required")
+	}
+	_, err := mail.ParseAddress(mai)
+	return err
+}
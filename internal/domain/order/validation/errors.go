@@ -0,0 +1,39 @@
+package validation
+
+import "strings"
+
+// Violation codes, stable for clients that react to a specific rule.
+const (
+	CodeRequired    = "required"
+	CodeInvalid     = "invalid"
+	CodeNotPositive = "not_positive"
+	CodeNegative    = "negative"
+	CodeOutOfRange  = "out_of_range"
+
+	CodeUnknownCurrency = "unknown_currency"
+)
+
+// Violation is a broken rule of a single field, addressed by its JSON path
+// (e.g. "items[2].brand").
+type Violation struct {
+	Path    string `json:"path"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (v Violation) Error() string { return v.Path + ": " + v.Message }
+
+// Violations lists every broken rule of an order in document order.
+type Violations []Violation
+
+func (vs Violations) Error() string {
+	msgs := make([]string, 0, len(vs))
+	for _, v := range vs {
+		msgs = append(msgs, v.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (vs *Violations) add(path, code, message string) {
+	*vs = append(*vs, Violation{Path: path, Code: code, Message: message})
+}
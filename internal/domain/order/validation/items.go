@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"fmt"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+)
+
+func validateItems(vs *Violations, items []domain.Item) {
+	if len(items) == 0 {
+		vs.add("items", CodeRequired, "items must not be empty")
+		return
+	}
+	for i, item := range items {
+		validateItem(vs, fmt.Sprintf("items[%d].", i), item)
+	}
+}
+
+func validateItem(vs *Violations, prefix string, item domain.Item) {
+	if item.ChrtId == "" {
+		vs.add(prefix+"chrt_id", CodeRequired, "chrt id must not be empty")
+	}
+	if item.Name == "" {
+		vs.add(prefix+"item_name", CodeRequired, "name must not be empty")
+	}
+	if item.Price <= 0 {
+		vs.add(prefix+"price", CodeNotPositive, "price must be more than zero")
+	}
+	if item.TotalPrice <= 0 {
+		vs.add(prefix+"total_price", CodeNotPositive, "total price must be more than zero")
+	}
+	if item.Brand == "" {
+		vs.add(prefix+"brand", CodeRequired, "brand must not be empty")
+	}
+	if item.NmId == "" {
+		vs.add(prefix+"nm_id", CodeRequired, "nm_id must not be empty")
+	}
+	if item.TrackNumber == "" {
+		vs.add(prefix+"track_number", CodeRequired, "track number must not be empty")
+	}
+}
@@ -0,0 +1,70 @@
+package validation
+
+import (
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"time"
+)
+
+// IsValidOrder returns Violations listing every broken rule, or nil.
+func IsValidOrder(order domain.Order) error {
+	if vs := ValidateOrder(order); len(vs) > 0 {
+		return vs
+	}
+	return nil
+}
+
+func ValidateOrder(order domain.Order) Violations {
+	return validate(order, true)
+}
+
+// IsValidPatchedOrder validates next, the result of patching cur. The rule
+// that date_created lies within the last year only applies when the patch
+// changes it, so older orders can still be patched.
+func IsValidPatchedOrder(cur, next domain.Order) error {
+	if vs := validate(next, !next.DateCreated.Equal(cur.DateCreated)); len(vs) > 0 {
+		return vs
+	}
+	return nil
+}
+
+func validate(order domain.Order, checkAge bool) Violations {
+	var vs Violations
+	validateOrderFields(&vs, order, checkAge)
+	validatePayment(&vs, order.Payment)
+	validateItems(&vs, order.Items)
+	validateDelivery(&vs, order.Delivery)
+	return vs
+}
+
+func isValidDate(date time.Time) bool {
+	if date.IsZero() {
+		return false
+	}
+	if date.After(time.Now()) || date.Before(time.Now().AddDate(-1, 0, 0)) {
+		return false
+	}
+	return true
+}
+
+func validateOrderFields(vs *Violations, order domain.Order, checkAge bool) {
+	if order.DeliveryService == "" {
+		vs.add("delivery_service", CodeRequired, "delivery service is required")
+	}
+	if order.TrackNumber == "" {
+		vs.add("track_number", CodeRequired, "track number is required")
+	}
+	if order.Entry == "" {
+		vs.add("entry", CodeRequired, "entry is required")
+	}
+	if order.Locale == "" {
+		vs.add("locale", CodeRequired, "locale is required")
+	}
+	if order.DateCreated.IsZero() {
+		vs.add("date_created", CodeRequired, "date created is required")
+	} else if checkAge && !isValidDate(order.DateCreated) {
+		vs.add("date_created", CodeOutOfRange, "date created must be within the last year")
+	}
+	if order.CustomerId == "" {
+		vs.add("customer_id", CodeRequired, "customer id is required")
+	}
+}
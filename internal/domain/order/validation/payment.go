@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"github.com/reybrally/order-service/internal/domain/money"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+)
+
+func validatePayment(vs *Violations, payment domain.Payment) {
+	if payment.Transaction == "" {
+		vs.add("payment.transaction", CodeRequired, "transaction payment is required")
+	}
+	if payment.Amount < 0 {
+		vs.add("payment.amount", CodeNegative, "amount is negative")
+	}
+	if payment.Currency == "" {
+		vs.add("payment.currency", CodeRequired, "currency is required")
+	} else if _, err := money.LookupCurrency(payment.Currency); err != nil {
+		vs.add("payment.currency", CodeUnknownCurrency, "currency must be an upper-case ISO 4217 code")
+	}
+	if payment.PaymentDt.IsZero() {
+		vs.add("payment.payment_dt", CodeRequired, "payment dt is required")
+	}
+}
@@ -0,0 +1,196 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/reybrally/order-service/internal/domain/order"
+)
+
+func validOrder() domain.Order {
+	now := time.Now().Add(-time.Hour)
+	return domain.Order{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerId:      "test",
+		DeliveryService: "meest",
+		DateCreated:     now,
+		Delivery: domain.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809",
+			City: "Kiryat Mozkin", Address: "Ploshad Mira 15", Region: "Kraiot",
+			Email: "test@gmail.com",
+		},
+		Payment: domain.Payment{
+			Transaction: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay",
+			Amount: 1817, PaymentDt: now, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317,
+		},
+		Items: []domain.Item{{
+			ChrtId: "9934930", TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest",
+			Name: "Mascaras", Sale: 30, TotalPrice: 317, NmId: "2389212", Brand: "Vivienne Sabo",
+		}},
+	}
+}
+
+func TestValidateOrder_Valid(t *testing.T) {
+	assert.Empty(t, ValidateOrder(validOrder()))
+	assert.NoError(t, IsValidOrder(validOrder()))
+}
+
+func TestValidateOrder_SingleViolation(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(o *domain.Order)
+		want   Violation
+	}{
+		{"delivery service", func(o *domain.Order) { o.DeliveryService = "" }, Violation{"delivery_service", CodeRequired, "delivery service is required"}},
+		{"track number", func(o *domain.Order) { o.TrackNumber = "" }, Violation{"track_number", CodeRequired, "track number is required"}},
+		{"entry", func(o *domain.Order) { o.Entry = "" }, Violation{"entry", CodeRequired, "entry is required"}},
+		{"locale", func(o *domain.Order) { o.Locale = "" }, Violation{"locale", CodeRequired, "locale is required"}},
+		{"customer id", func(o *domain.Order) { o.CustomerId = "" }, Violation{"customer_id", CodeRequired, "customer id is required"}},
+		{"date created missing", func(o *domain.Order) { o.DateCreated = time.Time{} }, Violation{"date_created", CodeRequired, "date created is required"}},
+		{"date created in future", func(o *domain.Order) { o.DateCreated = time.Now().Add(time.Hour) }, Violation{"date_created", CodeOutOfRange, "date created must be within the last year"}},
+		{"date created too old", func(o *domain.Order) { o.DateCreated = time.Now().AddDate(-2, 0, 0) }, Violation{"date_created", CodeOutOfRange, "date created must be within the last year"}},
+
+		{"payment transaction", func(o *domain.Order) { o.Payment.Transaction = "" }, Violation{"payment.transaction", CodeRequired, "transaction payment is required"}},
+		{"payment amount", func(o *domain.Order) { o.Payment.Amount = -1 }, Violation{"payment.amount", CodeNegative, "amount is negative"}},
+		{"payment currency", func(o *domain.Order) { o.Payment.Currency = "" }, Violation{"payment.currency", CodeRequired, "currency is required"}},
+		{"payment currency unknown", func(o *domain.Order) { o.Payment.Currency = "XYZ" }, Violation{"payment.currency", CodeUnknownCurrency, "currency must be an upper-case ISO 4217 code"}},
+		{"payment currency lower case", func(o *domain.Order) { o.Payment.Currency = "usd" }, Violation{"payment.currency", CodeUnknownCurrency, "currency must be an upper-case ISO 4217 code"}},
+		{"payment dt", func(o *domain.Order) { o.Payment.PaymentDt = time.Time{} }, Violation{"payment.payment_dt", CodeRequired, "payment dt is required"}},
+
+		{"no items", func(o *domain.Order) { o.Items = nil }, Violation{"items", CodeRequired, "items must not be empty"}},
+		{"item chrt id", func(o *domain.Order) { o.Items[0].ChrtId = "" }, Violation{"items[0].chrt_id", CodeRequired, "chrt id must not be empty"}},
+		{"item name", func(o *domain.Order) { o.Items[0].Name = "" }, Violation{"items[0].item_name", CodeRequired, "name must not be empty"}},
+		{"item price", func(o *domain.Order) { o.Items[0].Price = 0 }, Violation{"items[0].price", CodeNotPositive, "price must be more than zero"}},
+		{"item total price", func(o *domain.Order) { o.Items[0].TotalPrice = -5 }, Violation{"items[0].total_price", CodeNotPositive, "total price must be more than zero"}},
+		{"item brand", func(o *domain.Order) { o.Items[0].Brand = "" }, Violation{"items[0].brand", CodeRequired, "brand must not be empty"}},
+		{"item nm id", func(o *domain.Order) { o.Items[0].NmId = "" }, Violation{"items[0].nm_id", CodeRequired, "nm_id must not be empty"}},
+		{"item track number", func(o *domain.Order) { o.Items[0].TrackNumber = "" }, Violation{"items[0].track_number", CodeRequired, "track number must not be empty"}},
+
+		{"delivery name", func(o *domain.Order) { o.Delivery.Name = "" }, Violation{"delivery.name", CodeRequired, "delivery name is required"}},
+		{"delivery email missing", func(o *domain.Order) { o.Delivery.Email = "" }, Violation{"delivery.email", CodeRequired, "mail is required"}},
+		{"delivery phone", func(o *domain.Order) { o.Delivery.Phone = "" }, Violation{"delivery.phone", CodeRequired, "delivery phone is required"}},
+		{"delivery address", func(o *domain.Order) { o.Delivery.Address = "" }, Violation{"delivery.address", CodeRequired, "delivery address is required"}},
+		{"delivery city", func(o *domain.Order) { o.Delivery.City = "" }, Violation{"delivery.city", CodeRequired, "delivery city is required"}},
+		{"delivery region", func(o *domain.Order) { o.Delivery.Region = "" }, Violation{"delivery.region", CodeRequired, "delivery region is required"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := validOrder()
+			tc.mutate(&o)
+			assert.Equal(t, Violations{tc.want}, ValidateOrder(o))
+		})
+	}
+}
+
+func TestValidateOrder_InvalidEmail(t *testing.T) {
+	o := validOrder()
+	o.Delivery.Email = "not-an-address"
+
+	vs := ValidateOrder(o)
+	require.Len(t, vs, 1)
+	assert.Equal(t, "delivery.email", vs[0].Path)
+	assert.Equal(t, CodeInvalid, vs[0].Code)
+}
+
+func TestValidateOrder_CollectsEveryViolation(t *testing.T) {
+	o := validOrder()
+	second := o.Items[0]
+	second.Brand = ""
+	second.Price = 0
+	third := o.Items[0]
+	third.Brand = ""
+	o.Items = append(o.Items, second, third)
+	o.Locale = ""
+	o.Payment.Currency = ""
+	o.Delivery.City = ""
+
+	vs := ValidateOrder(o)
+
+	paths := make([]string, 0, len(vs))
+	for _, v := range vs {
+		paths = append(paths, v.Path)
+	}
+	assert.Equal(t, []string{
+		"locale",
+		"payment.currency",
+		"items[1].price",
+		"items[1].brand",
+		"items[2].brand",
+		"delivery.city",
+	}, paths)
+}
+
+func TestIsValidOrder_ReturnsViolations(t *testing.T) {
+	o := validOrder()
+	o.Entry = ""
+	o.Items[0].Brand = ""
+
+	err := IsValidOrder(o)
+	require.Error(t, err)
+
+	var vs Violations
+	require.True(t, errors.As(err, &vs))
+	assert.Len(t, vs, 2)
+	assert.Equal(t, "entry: entry is required; items[0].brand: brand must not be empty", err.Error())
+}
+
+func TestIsValidMail(t *testing.T) {
+	cases := []struct {
+		in    string
+		valid bool
+	}{
+		{"test@gmail.com", true},
+		{"Test Testov <test@gmail.com>", true},
+		{"", false},
+		{"plainaddress", false},
+		{"@no-local-part.com", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			err := IsValidMail(tc.in)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestIsValidPatchedOrder_DateCreatedAge(t *testing.T) {
+	old := validOrder()
+	old.DateCreated = time.Now().AddDate(-2, 0, 0)
+
+	next := old
+	next.Delivery.City = "Haifa"
+	assert.NoError(t, IsValidPatchedOrder(old, next), "unchanged date_created of an old order")
+
+	next.DateCreated = old.DateCreated.Add(time.Hour)
+	assert.Error(t, IsValidPatchedOrder(old, next), "patched date_created must still be recent")
+
+	next = validOrder()
+	next.DateCreated = time.Time{}
+	assert.Error(t, IsValidPatchedOrder(validOrder(), next), "date_created stays required")
+}
+
+func TestValidateOrder_EmailFormatCheckCanBeTurnedOff(t *testing.T) {
+	t.Cleanup(func() { SetEmailFormatCheck(true) })
+	o := validOrder()
+	o.Delivery.Email = "not-an-address"
+
+	require.Len(t, ValidateOrder(o), 1)
+	assert.Equal(t, CodeInvalid, ValidateOrder(o)[0].Code)
+
+	SetEmailFormatCheck(false)
+	assert.Empty(t, ValidateOrder(o))
+
+	o.Delivery.Email = ""
+	assert.Len(t, ValidateOrder(o), 1, "an empty address is still rejected")
+}
@@ -35,6 +35,13 @@ func LogError(message string, err error, fields logrus.Fields) {
 	Logger.WithFields(fields).Error(message)
 }
 
+func LogWarn(message string, fields logrus.Fields) {
+	if fields == nil {
+		fields = logrus.Fields{}
+	}
+	Logger.WithFields(fields).Warn(message)
+}
+
 func LogDebug(message string, fields logrus.Fields) {
 	if fields == nil {
 		fields = logrus.Fields{}
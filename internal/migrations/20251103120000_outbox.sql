@@ -0,0 +1,30 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id              BIGSERIAL   PRIMARY KEY,
+    aggregate_id    TEXT        NOT NULL,
+    topic           TEXT        NOT NULL,
+    event_type      TEXT        NOT NULL,
+    msg_key         BYTEA,
+    payload         JSONB       NOT NULL,
+    headers         JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at         TIMESTAMPTZ,
+    CONSTRAINT chk_outbox_status CHECK (status IN ('pending', 'sent', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_aggregate_pending ON outbox (aggregate_id, id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_sent_at ON outbox (sent_at) WHERE status = 'sent';
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_outbox_sent_at;
+DROP INDEX IF EXISTS idx_outbox_aggregate_pending;
+DROP INDEX IF EXISTS idx_outbox_pending;
+
+DROP TABLE IF EXISTS outbox;
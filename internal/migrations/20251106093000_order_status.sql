@@ -0,0 +1,31 @@
+-- +goose Up
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'created';
+
+ALTER TABLE orders
+    ADD CONSTRAINT chk_orders_status
+        CHECK (status IN ('created', 'paid', 'assembling', 'shipped', 'delivered', 'cancelled', 'returned'));
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id          BIGSERIAL   PRIMARY KEY,
+    order_uid   TEXT        NOT NULL
+        REFERENCES orders(order_uid) ON UPDATE CASCADE ON DELETE CASCADE,
+    from_status TEXT        NOT NULL,
+    to_status   TEXT        NOT NULL,
+    reason      TEXT        NOT NULL DEFAULT '',
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history (order_uid, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_orders_status;
+DROP INDEX IF EXISTS idx_order_status_history_order;
+
+DROP TABLE IF EXISTS order_status_history;
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_status;
+ALTER TABLE orders DROP COLUMN IF EXISTS status;
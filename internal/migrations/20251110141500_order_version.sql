@@ -0,0 +1,12 @@
+-- +goose Up
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE orders
+    ADD CONSTRAINT chk_orders_version_positive CHECK (version > 0);
+
+-- +goose Down
+
+ALTER TABLE orders DROP CONSTRAINT IF EXISTS chk_orders_version_positive;
+ALTER TABLE orders DROP COLUMN IF EXISTS version;
@@ -0,0 +1,20 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS order_revisions (
+    order_uid  TEXT        NOT NULL,
+    rev        BIGINT      NOT NULL,
+    op         TEXT        NOT NULL,
+    snapshot   JSONB       NOT NULL,
+    actor      TEXT        NOT NULL DEFAULT '',
+    request_id TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT pk_order_revisions PRIMARY KEY (order_uid, rev),
+    CONSTRAINT chk_order_revisions_op CHECK (op IN ('create', 'update', 'delete', 'status_change'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_revisions_created_at ON order_revisions (created_at);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_order_revisions_created_at;
+DROP TABLE IF EXISTS order_revisions;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE order_revisions DROP CONSTRAINT IF EXISTS chk_order_revisions_op;
+ALTER TABLE order_revisions
+    ADD CONSTRAINT chk_order_revisions_op CHECK (op IN ('create', 'update', 'delete', 'restore', 'status_change'));
+
+-- +goose Down
+
+ALTER TABLE order_revisions DROP CONSTRAINT IF EXISTS chk_order_revisions_op;
+ALTER TABLE order_revisions
+    ADD CONSTRAINT chk_order_revisions_op CHECK (op IN ('create', 'update', 'delete', 'status_change'));
+
+DROP INDEX IF EXISTS idx_orders_deleted_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- rate is the price of one major unit of base in quote, valid from valid_from
+-- until the next row of the same pair.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base       TEXT           NOT NULL,
+    quote      TEXT           NOT NULL,
+    valid_from TIMESTAMPTZ    NOT NULL,
+    rate       NUMERIC(24,12) NOT NULL,
+    loaded_at  TIMESTAMPTZ    NOT NULL DEFAULT now(),
+    CONSTRAINT pk_exchange_rates PRIMARY KEY (base, quote, valid_from),
+    CONSTRAINT chk_exchange_rates_base_iso3  CHECK (base  ~ '^[A-Z]{3}$'),
+    CONSTRAINT chk_exchange_rates_quote_iso3 CHECK (quote ~ '^[A-Z]{3}$'),
+    CONSTRAINT chk_exchange_rates_rate_pos   CHECK (rate > 0)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS exchange_rates;
@@ -0,0 +1,103 @@
+-- +goose Up
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- One search document per order, kept in sync by triggers. haystack is the
+-- plain text for trigram matching; document weighs identifiers (A) over
+-- item names and brands (B) over delivery name and city (C).
+CREATE TABLE IF NOT EXISTS order_search_docs (
+    order_uid  TEXT        PRIMARY KEY
+        REFERENCES orders(order_uid) ON UPDATE CASCADE ON DELETE CASCADE,
+    haystack   TEXT        NOT NULL,
+    document   TSVECTOR    NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_search_docs_document ON order_search_docs USING gin (document);
+CREATE INDEX IF NOT EXISTS idx_order_search_docs_haystack ON order_search_docs USING gin (haystack gin_trgm_ops);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION refresh_order_search_doc(uid TEXT) RETURNS void AS $$
+DECLARE
+    ids      TEXT;
+    goods    TEXT;
+    delivery TEXT;
+BEGIN
+    SELECT concat_ws(' ', o.order_uid, o.track_number, o.customer_id, p.transaction)
+      INTO ids
+      FROM orders o
+      LEFT JOIN payments p ON p.order_uid = o.order_uid
+     WHERE o.order_uid = uid;
+    IF NOT FOUND THEN
+        DELETE FROM order_search_docs WHERE order_uid = uid;
+        RETURN;
+    END IF;
+
+    SELECT string_agg(concat_ws(' ', i.item_name, i.brand), ' ' ORDER BY i.chrt_id)
+      INTO goods
+      FROM order_items i
+     WHERE i.order_uid = uid;
+
+    SELECT concat_ws(' ', d.delivery_name, d.city)
+      INTO delivery
+      FROM deliveries d
+     WHERE d.order_uid = uid;
+
+    INSERT INTO order_search_docs (order_uid, haystack, document, updated_at)
+    VALUES (
+        uid,
+        concat_ws(' ', ids, goods, delivery),
+        setweight(to_tsvector('simple', coalesce(ids, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(goods, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(delivery, '')), 'C'),
+        now()
+    )
+    ON CONFLICT (order_uid) DO UPDATE SET
+        haystack   = EXCLUDED.haystack,
+        document   = EXCLUDED.document,
+        updated_at = EXCLUDED.updated_at;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION trg_refresh_order_search_doc() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM refresh_order_search_doc(OLD.order_uid);
+    END IF;
+    IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.order_uid IS DISTINCT FROM OLD.order_uid) THEN
+        PERFORM refresh_order_search_doc(NEW.order_uid);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER trg_orders_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, track_number, customer_id ON orders
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+CREATE TRIGGER trg_payments_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, transaction OR DELETE ON payments
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+CREATE TRIGGER trg_deliveries_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, delivery_name, city OR DELETE ON deliveries
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+CREATE TRIGGER trg_order_items_search_doc
+    AFTER INSERT OR UPDATE OF order_uid, item_name, brand OR DELETE ON order_items
+    FOR EACH ROW EXECUTE FUNCTION trg_refresh_order_search_doc();
+
+SELECT refresh_order_search_doc(order_uid) FROM orders;
+
+-- +goose Down
+
+DROP TRIGGER IF EXISTS trg_order_items_search_doc ON order_items;
+DROP TRIGGER IF EXISTS trg_deliveries_search_doc ON deliveries;
+DROP TRIGGER IF EXISTS trg_payments_search_doc ON payments;
+DROP TRIGGER IF EXISTS trg_orders_search_doc ON orders;
+DROP FUNCTION IF EXISTS trg_refresh_order_search_doc();
+DROP FUNCTION IF EXISTS refresh_order_search_doc(TEXT);
+DROP TABLE IF EXISTS order_search_docs;
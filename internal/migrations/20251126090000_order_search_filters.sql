@@ -0,0 +1,29 @@
+-- +goose Up
+
+-- Support lookups by item, delivery and amount; brand, city, region and email
+-- are searched case-insensitively.
+CREATE INDEX IF NOT EXISTS idx_items_nm_id       ON order_items (nm_id);
+CREATE INDEX IF NOT EXISTS idx_items_chrt_id     ON order_items (chrt_id);
+CREATE INDEX IF NOT EXISTS idx_items_rid         ON order_items (rid);
+CREATE INDEX IF NOT EXISTS idx_items_brand_lower ON order_items (lower(brand));
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_city_lower   ON deliveries (lower(city));
+CREATE INDEX IF NOT EXISTS idx_deliveries_region_lower ON deliveries (lower(region));
+CREATE INDEX IF NOT EXISTS idx_deliveries_phone        ON deliveries (phone);
+CREATE INDEX IF NOT EXISTS idx_deliveries_email_lower  ON deliveries (lower(email));
+
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service ON orders (delivery_service);
+CREATE INDEX IF NOT EXISTS idx_payments_amount         ON payments (amount);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS idx_payments_amount;
+DROP INDEX IF EXISTS idx_orders_delivery_service;
+DROP INDEX IF EXISTS idx_deliveries_email_lower;
+DROP INDEX IF EXISTS idx_deliveries_phone;
+DROP INDEX IF EXISTS idx_deliveries_region_lower;
+DROP INDEX IF EXISTS idx_deliveries_city_lower;
+DROP INDEX IF EXISTS idx_items_brand_lower;
+DROP INDEX IF EXISTS idx_items_rid;
+DROP INDEX IF EXISTS idx_items_chrt_id;
+DROP INDEX IF EXISTS idx_items_nm_id;
@@ -0,0 +1,20 @@
+-- +goose Up
+
+-- Idempotency-Key of POST /orders: the fingerprint of the first request and,
+-- once it has finished, the response replayed to its retries. status_code is
+-- NULL while that request is in flight.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          TEXT PRIMARY KEY CHECK (length(key) BETWEEN 1 AND 255),
+    fingerprint  TEXT NOT NULL,
+    status_code  INT,
+    headers      JSONB,
+    body         BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS idempotency_keys;
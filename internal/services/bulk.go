@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// ImportOrders stores list applying the checks and events of
+// CreateOrUpdateOrder to every order; results[i] is the outcome of list[i].
+// Orders failing the consistency check never reach the repository, and with
+// opts.Atomic one such order keeps the whole import from being written.
+func (serv *OrderService) ImportOrders(ctx context.Context, list []domain.Order, opts orders.BulkOptions) ([]orders.BulkResult, error) {
+	logging.LogInfo("Importing orders", logrus.Fields{"count": len(list), "atomic": opts.Atomic, "batch_size": opts.BatchSize})
+
+	results := make([]orders.BulkResult, len(list))
+	records := make([]orders.BulkRecord, 0, len(list))
+	pos := make([]int, 0, len(list))
+	for i, o := range list {
+		if o.OrderUID == "" {
+			o.OrderUID = uuid.New().String()
+		}
+		if err := serv.checkConsistency(o); err != nil {
+			results[i].Err = err
+			continue
+		}
+		events, err := serv.upsertedEvents(ctx, o.OrderUID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, orders.BulkRecord{Order: o, Events: events})
+		pos = append(pos, i)
+	}
+
+	if opts.Atomic && len(records) < len(list) {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Err = orders.ErrBulkAborted
+			}
+		}
+		logging.LogError("Bulk import rejected before writing", nil, logrus.Fields{"count": len(list), "rejected": len(list) - len(records)})
+		return results, nil
+	}
+
+	stored, err := serv.repo.CreateOrUpdateOrders(ctx, records, opts)
+	for k, res := range stored {
+		results[pos[k]] = res
+		if res.Err == nil {
+			_ = serv.cacheService.Set(res.Order.OrderUID, res.Order)
+		}
+	}
+	if err != nil {
+		logging.LogError("Error importing orders", err, logrus.Fields{"count": len(list)})
+		return results, err
+	}
+	return results, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// ConvertPayments converts the payment amount of every order into the
+// reporting currency to, using the rate in effect at its payment_dt. The
+// result follows the order of list. An order without a payment converts to
+// zero; a missing rate fails the whole call with orders.ErrNoExchangeRate.
+func (serv *OrderService) ConvertPayments(ctx context.Context, list []domain.Order, to string) ([]orders.Conversion, error) {
+	target, err := money.LookupCurrency(to)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]orders.Conversion, len(list))
+	var (
+		reqs []orders.RateRequest
+		idx  []int
+	)
+	for i, o := range list {
+		out[i] = orders.Conversion{OrderUID: o.OrderUID, Amount: money.Money{Currency: target}}
+		if o.Payment.Transaction == "" {
+			continue
+		}
+		if o.Payment.Currency == to {
+			out[i].Rate = money.Identity(to)
+			continue
+		}
+		reqs = append(reqs, orders.RateRequest{Base: o.Payment.Currency, At: o.Payment.PaymentDt})
+		idx = append(idx, i)
+	}
+
+	rates, err := serv.rates.RatesAt(ctx, to, reqs)
+	if err != nil {
+		logging.LogError("Error loading exchange rates", err, logrus.Fields{"quote": to, "count": len(reqs)})
+		return nil, err
+	}
+	for k, i := range idx {
+		if rates[k].Value == nil {
+			return nil, fmt.Errorf("%w: %s to %s at %s (order %s)", orders.ErrNoExchangeRate,
+				reqs[k].Base, to, reqs[k].At.Format("2006-01-02T15:04:05Z07:00"), list[i].OrderUID)
+		}
+		out[i].Rate = rates[k]
+	}
+
+	for i, o := range list {
+		if out[i].Rate.Value == nil {
+			continue
+		}
+		m, err := o.Payment.Money(o.Payment.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("order %s: %w", o.OrderUID, err)
+		}
+		if out[i].Amount, err = m.Convert(out[i].Rate); err != nil {
+			logging.LogError("Error converting payment amount", err, logrus.Fields{"order_uid": o.OrderUID, "quote": to})
+			return nil, fmt.Errorf("order %s: %w", o.OrderUID, err)
+		}
+	}
+	return out, nil
+}
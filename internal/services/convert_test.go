@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/adapters/cache"
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// fixedRates answers RatesAt with the rate of each base from a fixed map; a
+// base missing from the map has no rate. SaveRates panics.
+type fixedRates struct {
+	orders.ExchangeRates
+	byBase map[string]string
+}
+
+func (r fixedRates) RatesAt(_ context.Context, quote string, reqs []orders.RateRequest) ([]money.Rate, error) {
+	out := make([]money.Rate, len(reqs))
+	for i, req := range reqs {
+		out[i] = money.Rate{Base: req.Base, Quote: quote}
+		if v, ok := r.byBase[req.Base]; ok {
+			out[i].Value, _ = new(big.Rat).SetString(v)
+		}
+	}
+	return out, nil
+}
+
+func paidOrder(uid, currency string, amount int64) domain.Order {
+	return domain.Order{OrderUID: uid, Payment: domain.Payment{
+		Transaction: "tx-" + uid,
+		Currency:    currency,
+		Amount:      amount,
+		PaymentDt:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+	}}
+}
+
+func TestConvertPayments(t *testing.T) {
+	logging.InitLogger()
+	rates := fixedRates{byBase: map[string]string{"USD": "90", "JPY": "1000000000000"}}
+	serv := NewOrderService(nil, cache.NewCacheService(1), rates, "orders-events", domain.ConsistencyStrict)
+	ctx := context.Background()
+
+	t.Run("converts at the rate", func(t *testing.T) {
+		got, err := serv.ConvertPayments(ctx, []domain.Order{paidOrder("a", "USD", 1050), paidOrder("b", "RUB", 700), {OrderUID: "c"}}, "RUB")
+		require.NoError(t, err)
+		require.Len(t, got, 3)
+		assert.Equal(t, int64(94500), got[0].Amount.Amount)
+		assert.Equal(t, int64(700), got[1].Amount.Amount)
+		assert.Equal(t, int64(0), got[2].Amount.Amount)
+		assert.Nil(t, got[2].Rate.Value)
+	})
+
+	t.Run("missing rate", func(t *testing.T) {
+		_, err := serv.ConvertPayments(ctx, []domain.Order{paidOrder("a", "EUR", 100)}, "RUB")
+		assert.ErrorIs(t, err, orders.ErrNoExchangeRate)
+	})
+
+	t.Run("overflow is not a missing rate", func(t *testing.T) {
+		_, err := serv.ConvertPayments(ctx, []domain.Order{paidOrder("a", "JPY", math.MaxInt64/2)}, "RUB")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, money.ErrInvalidRate)
+		assert.NotErrorIs(t, err, orders.ErrNoExchangeRate)
+		assert.Contains(t, err.Error(), "order a")
+	})
+}
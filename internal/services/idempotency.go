@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// RunIdempotencyPurger deletes expired idempotency keys every interval until
+// ctx is done. Expired keys are already ignored and reusable; purging only
+// keeps the table small.
+func RunIdempotencyPurger(ctx context.Context, store orders.IdempotencyStore, interval time.Duration) {
+	if interval <= 0 {
+		logging.LogInfo("Idempotency key purger disabled", logrus.Fields{})
+		return
+	}
+	logging.LogInfo("Idempotency key purger started", logrus.Fields{"interval": interval.String()})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := store.PurgeExpired(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logging.LogError("Purging idempotency keys failed", err, logrus.Fields{})
+		case n > 0:
+			logging.LogInfo("Purged expired idempotency keys", logrus.Fields{"deleted": n})
+		}
+
+		select {
+		case <-ctx.Done():
+			logging.LogInfo("Idempotency key purger stopped", logrus.Fields{})
+			return
+		case <-ticker.C:
+		}
+	}
+}
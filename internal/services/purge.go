@@ -0,0 +1,49 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// RunDeletedPurger hard-deletes orders that have been soft-deleted for longer
+// than retention, checking every interval until ctx is done.
+func (serv *OrderService) RunDeletedPurger(ctx context.Context, interval, retention time.Duration) {
+	if interval <= 0 || retention <= 0 {
+		logging.LogInfo("Deleted orders purger disabled", logrus.Fields{})
+		return
+	}
+	logging.LogInfo("Deleted orders purger started", logrus.Fields{
+		"interval": interval.String(), "retention": retention.String(),
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		serv.purgeDeleted(ctx, retention)
+
+		select {
+		case <-ctx.Done():
+			logging.LogInfo("Deleted orders purger stopped", logrus.Fields{})
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (serv *OrderService) purgeDeleted(ctx context.Context, retention time.Duration) {
+	before := time.Now().UTC().Add(-retention)
+	n, err := serv.repo.PurgeDeleted(ctx, before)
+	if err != nil {
+		if ctx.Err() == nil {
+			logging.LogError("Purging deleted orders failed", err, logrus.Fields{"before": before})
+		}
+		return
+	}
+	if n > 0 {
+		logging.LogInfo("Purged deleted orders", logrus.Fields{"deleted": n, "before": before})
+	}
+}
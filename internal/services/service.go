@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,17 +19,19 @@ import (
 type OrderService struct {
 	repo         orders.OrderRepo
 	cacheService cache.Cache
+	rates        orders.ExchangeRates
 
-	producer    kaf.Producer
 	eventsTopic string
+	consistency domain.ConsistencyMode
 }
 
-func NewOrderService(repo orders.OrderRepo, cache cache.Cache, producer kaf.Producer, eventsTopic string) *OrderService {
+func NewOrderService(repo orders.OrderRepo, cache cache.Cache, rates orders.ExchangeRates, eventsTopic string, consistency domain.ConsistencyMode) *OrderService {
 	return &OrderService{
 		repo:         repo,
 		cacheService: cache,
-		producer:     producer,
+		rates:        rates,
 		eventsTopic:  eventsTopic,
+		consistency:  consistency,
 	}
 }
 
@@ -39,7 +42,16 @@ func (serv *OrderService) CreateOrUpdateOrder(ctx context.Context, or domain.Ord
 		or.OrderUID = uuid.New().String()
 	}
 
-	ord, err := serv.repo.CreateOrUpdateOrder(ctx, or)
+	if err := serv.checkConsistency(or); err != nil {
+		return domain.Order{}, err
+	}
+
+	events, err := serv.upsertedEvents(ctx, or.OrderUID)
+	if err != nil {
+		return domain.Order{}, err
+	}
+
+	ord, err := serv.repo.CreateOrUpdateOrder(ctx, or, events...)
 	if err != nil {
 		logging.LogError("Error during CreateOrUpdateOrder", err, logrus.Fields{"order_uid": or.OrderUID})
 		return domain.Order{}, err
@@ -48,21 +60,79 @@ func (serv *OrderService) CreateOrUpdateOrder(ctx context.Context, or domain.Ord
 	_ = serv.cacheService.Set(ord.OrderUID, ord)
 	logging.LogInfo("Order created or updated successfully", logrus.Fields{"order_uid": ord.OrderUID})
 
+	return ord, nil
+}
+
+// checkConsistency rejects an order whose totals do not add up in strict mode
+// and only logs it in warn mode.
+func (serv *OrderService) checkConsistency(or domain.Order) error {
+	issues := or.CheckConsistency()
+	if len(issues) == 0 {
+		return nil
+	}
+	fields := logrus.Fields{"order_uid": or.OrderUID, "issues": issues, "mode": serv.consistency}
+	if serv.consistency != domain.ConsistencyWarn {
+		err := fmt.Errorf("%w: %w", orders.ErrInvalidData, domain.InconsistencyError(issues))
+		logging.LogError("Order totals are inconsistent, rejecting", err, fields)
+		return err
+	}
+	logging.LogWarn("Order totals are inconsistent, storing anyway", fields)
+	return nil
+}
+
+// eventSource is the Meta.Source of events caused by the actor of ctx;
+// changes without an actor come from the HTTP API.
+func eventSource(ctx context.Context) string {
+	if src := orders.ActorFromContext(ctx).Source; src != "" {
+		return src
+	}
+	return "http"
+}
+
+func (serv *OrderService) upsertedEvents(ctx context.Context, uid string) ([]orders.OutboxEvent, error) {
 	env := kaf.Envelope[kaf.OrderUpserted]{
 		EventType:  "order.upserted",
 		Version:    1,
 		OccurredAt: time.Now().UTC(),
-		EntityID:   ord.OrderUID,
-		Payload:    kaf.OrderUpserted{OrderUID: ord.OrderUID},
-		Meta:       kaf.Meta{Producer: "order-service", Source: "http"},
+		EntityID:   uid,
+		Payload:    kaf.OrderUpserted{OrderUID: uid},
+		Meta:       kaf.Meta{Producer: "order-service", Source: eventSource(ctx)},
 	}
-	if serv.producer != nil && serv.eventsTopic != "" {
-		if err := kaf.PublishEnvelope(ctx, serv.producer, serv.eventsTopic, []byte(ord.OrderUID), env, nil); err != nil {
-			logging.LogError("Failed to publish order.upserted", err, logrus.Fields{"order_uid": ord.OrderUID})
-		}
+	events, err := outboxEvents(serv.eventsTopic, env)
+	if err != nil {
+		logging.LogError("Failed to build order.upserted event", err, logrus.Fields{"order_uid": uid})
+		return nil, err
 	}
+	return events, nil
+}
 
-	return ord, nil
+// PatchOrder applies apply to the current state of the order and stores the
+// result. The write is conditional on the version that was read, so a
+// concurrent change makes it fail with orders.ErrConflict. A non-zero
+// expectedVersion must also match the current version.
+func (serv *OrderService) PatchOrder(ctx context.Context, id string, expectedVersion int64, apply func(domain.Order) (domain.Order, error)) (domain.Order, error) {
+	logging.LogInfo("Attempting to patch order", logrus.Fields{"order_uid": id})
+
+	cur, err := serv.repo.GetOrder(ctx, id)
+	if err != nil {
+		logging.LogError("Error fetching order for patch", err, logrus.Fields{"order_uid": id})
+		return domain.Order{}, err
+	}
+	if expectedVersion > 0 && cur.Version != expectedVersion {
+		logging.LogError("Order version mismatch", nil, logrus.Fields{"order_uid": id, "expected_version": expectedVersion, "version": cur.Version})
+		return domain.Order{}, orders.ErrConflict
+	}
+
+	next, err := apply(cur)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	next.OrderUID = cur.OrderUID
+	next.Status = cur.Status
+	next.DeletedAt = nil
+	next.Version = cur.Version
+
+	return serv.CreateOrUpdateOrder(ctx, next)
 }
 
 func (serv *OrderService) GetOrder(ctx context.Context, id string) (domain.Order, error) {
@@ -84,6 +154,79 @@ func (serv *OrderService) GetOrder(ctx context.Context, id string) (domain.Order
 	return ord, nil
 }
 
+// GetOrders resolves ids in one go: hits come from the cache, the misses are
+// loaded from the repository together and cached. Found orders keep the order
+// of ids; missing lists the ids without a live order. Duplicate ids are
+// resolved once.
+func (serv *OrderService) GetOrders(ctx context.Context, ids []string) (found []domain.Order, missing []string, err error) {
+	seen := make(map[string]struct{}, len(ids))
+	uniq := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			uniq = append(uniq, id)
+		}
+	}
+
+	byID := make(map[string]domain.Order, len(uniq))
+	var misses []string
+	for _, id := range uniq {
+		if ord, err := serv.cacheService.Get(id); err == nil {
+			byID[id] = ord
+		} else {
+			misses = append(misses, id)
+		}
+	}
+
+	if len(misses) > 0 {
+		loaded, err := serv.repo.GetOrders(ctx, misses)
+		if err != nil {
+			logging.LogError("Error fetching orders from repository", err, logrus.Fields{"count": len(misses)})
+			return nil, nil, err
+		}
+		for _, ord := range loaded {
+			byID[ord.OrderUID] = ord
+			_ = serv.cacheService.Set(ord.OrderUID, ord)
+		}
+	}
+
+	found = make([]domain.Order, 0, len(byID))
+	missing = []string{}
+	for _, id := range uniq {
+		if ord, ok := byID[id]; ok {
+			found = append(found, ord)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	logging.LogInfo("Orders fetched", logrus.Fields{"requested": len(uniq), "cache_misses": len(misses), "missing": len(missing)})
+	return found, missing, nil
+}
+
+// TrackOrder returns the live order with trackNumber, resolving it through the
+// cache's track number index before falling back to the repository. Only the
+// repository's answer is indexed, as several orders may share a track number.
+func (serv *OrderService) TrackOrder(ctx context.Context, trackNumber string) (domain.Order, error) {
+	logging.LogInfo("Fetching order by track number", logrus.Fields{"track_number": trackNumber})
+
+	if uid, err := serv.cacheService.GetUIDByTrack(trackNumber); err == nil {
+		// The index may point at an order whose track number has changed.
+		if ord, err := serv.cacheService.Get(uid); err == nil && ord.TrackNumber == trackNumber && ord.DeletedAt == nil {
+			logging.LogInfo("Order found in cache by track number", logrus.Fields{"track_number": trackNumber, "order_uid": uid})
+			return ord, nil
+		}
+	}
+
+	ord, err := serv.repo.GetOrderByTrack(ctx, trackNumber)
+	if err != nil {
+		logging.LogError("Error fetching order by track number", err, logrus.Fields{"track_number": trackNumber})
+		return domain.Order{}, err
+	}
+	_ = serv.cacheService.Set(ord.OrderUID, ord)
+	_ = serv.cacheService.SetTrack(trackNumber, ord.OrderUID)
+	return ord, nil
+}
+
 func (serv *OrderService) DeleteOrder(ctx context.Context, id string) error {
 	logging.LogInfo("Attempting to delete order", logrus.Fields{"order_uid": id})
 
@@ -93,7 +236,21 @@ func (serv *OrderService) DeleteOrder(ctx context.Context, id string) error {
 		return err
 	}
 
-	if err := serv.repo.DeleteOrder(ctx, id); err != nil {
+	env := kaf.Envelope[kaf.OrderDeleted]{
+		EventType:  "order.deleted",
+		Version:    1,
+		OccurredAt: time.Now().UTC(),
+		EntityID:   id,
+		Payload:    kaf.OrderDeleted{OrderUID: id},
+		Meta:       kaf.Meta{Producer: "order-service", Source: eventSource(ctx)},
+	}
+	events, err := outboxEvents(serv.eventsTopic, env)
+	if err != nil {
+		logging.LogError("Failed to build order.deleted event", err, logrus.Fields{"order_uid": id})
+		return err
+	}
+
+	if err := serv.repo.DeleteOrder(ctx, id, events...); err != nil {
 		logging.LogError("Error deleting order from repository", err, logrus.Fields{"order_uid": id})
 		return err
 	}
@@ -101,32 +258,171 @@ func (serv *OrderService) DeleteOrder(ctx context.Context, id string) error {
 	_ = serv.cacheService.Delete(id)
 	logging.LogInfo("Order deleted successfully", logrus.Fields{"order_uid": id})
 
-	env := kaf.Envelope[kaf.OrderDeleted]{
-		EventType:  "order.deleted",
+	return nil
+}
+
+func (serv *OrderService) RestoreOrder(ctx context.Context, id string) (domain.Order, error) {
+	logging.LogInfo("Attempting to restore order", logrus.Fields{"order_uid": id})
+
+	env := kaf.Envelope[kaf.OrderRestored]{
+		EventType:  "order.restored",
 		Version:    1,
 		OccurredAt: time.Now().UTC(),
 		EntityID:   id,
-		Payload:    kaf.OrderDeleted{OrderUID: id},
-		Meta:       kaf.Meta{Producer: "order-service", Source: "http"},
+		Payload:    kaf.OrderRestored{OrderUID: id},
+		Meta:       kaf.Meta{Producer: "order-service", Source: eventSource(ctx)},
 	}
-	if serv.producer != nil && serv.eventsTopic != "" {
-		if err := kaf.PublishEnvelope(ctx, serv.producer, serv.eventsTopic, []byte(id), env, nil); err != nil {
-			logging.LogError("Failed to publish order.deleted", err, logrus.Fields{"order_uid": id})
-		}
+	events, err := outboxEvents(serv.eventsTopic, env)
+	if err != nil {
+		logging.LogError("Failed to build order.restored event", err, logrus.Fields{"order_uid": id})
+		return domain.Order{}, err
 	}
 
-	return nil
+	ord, err := serv.repo.RestoreOrder(ctx, id, events...)
+	if err != nil {
+		logging.LogError("Error restoring order", err, logrus.Fields{"order_uid": id})
+		return domain.Order{}, err
+	}
+
+	_ = serv.cacheService.Set(ord.OrderUID, ord)
+	logging.LogInfo("Order restored successfully", logrus.Fields{"order_uid": id})
+	return ord, nil
 }
 
-func (serv *OrderService) SearchOrder(ctx context.Context, filters orders.SearchFilters, req orders.PageRequest) ([]domain.Order, error) {
-	logging.LogInfo("Searching for orders", logrus.Fields{"filters": filters, "page_request": req})
+func (serv *OrderService) TransitionOrder(ctx context.Context, id string, to domain.Status, reason string) (domain.StatusChange, error) {
+	logging.LogInfo("Attempting to transition order", logrus.Fields{"order_uid": id, "to": to})
 
-	list, err := serv.repo.SearchOrders(ctx, filters, req)
+	cur, err := serv.repo.GetOrder(ctx, id)
 	if err != nil {
-		logging.LogError("Error searching orders in repository", err, logrus.Fields{"filters": filters, "page_request": req})
+		logging.LogError("Error fetching order for transition", err, logrus.Fields{"order_uid": id})
+		return domain.StatusChange{}, err
+	}
+
+	ch, err := cur.Transition(to, reason, time.Now().UTC())
+	if err != nil {
+		logging.LogError("Transition rejected", err, logrus.Fields{"order_uid": id, "from": cur.Status, "to": to})
+		return domain.StatusChange{}, err
+	}
+
+	env := kaf.Envelope[kaf.OrderStatusChanged]{
+		EventType:  "order.status_changed",
+		Version:    1,
+		OccurredAt: ch.ChangedAt,
+		EntityID:   id,
+		Payload: kaf.OrderStatusChanged{
+			OrderUID:  id,
+			From:      string(ch.From),
+			To:        string(ch.To),
+			Reason:    ch.Reason,
+			ChangedAt: ch.ChangedAt,
+		},
+		Meta: kaf.Meta{Producer: "order-service", Source: eventSource(ctx)},
+	}
+	events, err := outboxEvents(serv.eventsTopic, env)
+	if err != nil {
+		logging.LogError("Failed to build order.status_changed event", err, logrus.Fields{"order_uid": id})
+		return domain.StatusChange{}, err
+	}
+
+	if err := serv.repo.ChangeStatus(ctx, ch, events...); err != nil {
+		logging.LogError("Error changing order status", err, logrus.Fields{"order_uid": id})
+		return domain.StatusChange{}, err
+	}
+
+	_ = serv.cacheService.Delete(id)
+	logging.LogInfo("Order transitioned", logrus.Fields{"order_uid": id, "from": ch.From, "to": ch.To})
+	return ch, nil
+}
+
+func (serv *OrderService) StatusHistory(ctx context.Context, id string) ([]domain.StatusChange, error) {
+	logging.LogInfo("Fetching status history", logrus.Fields{"order_uid": id})
+
+	if _, err := serv.GetOrder(ctx, id); err != nil {
+		return nil, err
+	}
+	list, err := serv.repo.StatusHistory(ctx, id)
+	if err != nil {
+		logging.LogError("Error fetching status history", err, logrus.Fields{"order_uid": id})
 		return nil, err
 	}
+	return list, nil
+}
 
-	logging.LogInfo("Orders found", logrus.Fields{"count": len(list)})
+func (serv *OrderService) OrderHistory(ctx context.Context, id string) ([]domain.Revision, error) {
+	logging.LogInfo("Fetching order history", logrus.Fields{"order_uid": id})
+
+	list, err := serv.repo.Revisions(ctx, id)
+	if err != nil {
+		logging.LogError("Error fetching order history", err, logrus.Fields{"order_uid": id})
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, orders.ErrNotFound
+	}
 	return list, nil
 }
+
+func (serv *OrderService) OrderRevision(ctx context.Context, id string, rev int64) (domain.Revision, error) {
+	logging.LogInfo("Fetching order revision", logrus.Fields{"order_uid": id, "rev": rev})
+
+	out, err := serv.repo.Revision(ctx, id, rev)
+	if err != nil {
+		logging.LogError("Error fetching order revision", err, logrus.Fields{"order_uid": id, "rev": rev})
+		return domain.Revision{}, err
+	}
+	return out, nil
+}
+
+func (serv *OrderService) SearchOrder(ctx context.Context, filters orders.SearchFilters, req orders.PageRequest) (orders.Page, error) {
+	logging.LogInfo("Searching for orders", logrus.Fields{"filters": filters, "page_request": req})
+
+	page, err := serv.repo.SearchOrders(ctx, filters, req)
+	if err != nil {
+		logging.LogError("Error searching orders in repository", err, logrus.Fields{"filters": filters, "page_request": req})
+		return orders.Page{}, err
+	}
+
+	logging.LogInfo("Orders found", logrus.Fields{"count": len(page.Orders)})
+	return page, nil
+}
+
+// ExportOrders passes every order matching filters to fn straight from the
+// repository, bypassing the cache.
+func (serv *OrderService) ExportOrders(ctx context.Context, filters orders.SearchFilters, sortBy, sortDir string, fn func(domain.Order) error) error {
+	logging.LogInfo("Exporting orders", logrus.Fields{"filters": filters, "sort_by": sortBy, "sort_dir": sortDir})
+	return serv.repo.ExportOrders(ctx, filters, sortBy, sortDir, fn)
+}
+
+// ConsistencyReport lists stored orders whose totals do not add up, limit
+// orders per page starting after the order_uid in cursor.
+func (serv *OrderService) ConsistencyReport(ctx context.Context, cursor string, limit int) (orders.ConsistencyReport, error) {
+	page, err := serv.repo.InconsistentOrders(ctx, cursor, limit)
+	if err != nil {
+		logging.LogError("Error building consistency report", err, logrus.Fields{"cursor": cursor})
+		return orders.ConsistencyReport{}, err
+	}
+
+	report := orders.ConsistencyReport{
+		Orders:     make([]orders.InconsistentOrder, 0, len(page.Orders)),
+		NextCursor: page.NextCursor,
+	}
+	for _, o := range page.Orders {
+		report.Orders = append(report.Orders, orders.InconsistentOrder{
+			OrderUID: o.OrderUID,
+			Issues:   o.CheckConsistency(),
+		})
+	}
+	logging.LogInfo("Consistency report built", logrus.Fields{"orders": len(report.Orders), "cursor": cursor})
+	return report, nil
+}
+
+func outboxEvents[T any](topic string, env kaf.Envelope[T]) ([]orders.OutboxEvent, error) {
+	if topic == "" {
+		return nil, nil
+	}
+	ev, err := kaf.NewOutboxEvent(topic, []byte(env.EntityID), env, nil)
+	if err != nil {
+		return nil, err
+	}
+	return []orders.OutboxEvent{ev}, nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	"github.com/reybrally/order-service/internal/domain/money"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// OrderStats aggregates the orders matching f. Without a reporting currency
+// a key paid in several currencies yields one group per currency, told apart
+// by Revenue.Currency. With one, groups of a key are merged and an order
+// without a rate fails the call with orders.ErrNoExchangeRate.
+func (serv *OrderService) OrderStats(ctx context.Context, f orders.SearchFilters, req orders.StatsRequest) (orders.Stats, error) {
+	var target money.Currency
+	if req.ReportingCurrency != "" {
+		c, err := money.LookupCurrency(req.ReportingCurrency)
+		if err != nil {
+			return orders.Stats{}, err
+		}
+		target = c
+	}
+
+	rows, err := serv.repo.OrderStats(ctx, f, req)
+	if err != nil {
+		logging.LogError("Error computing order stats", err, logrus.Fields{"group_by": req.GroupBy})
+		return orders.Stats{}, err
+	}
+
+	stats := orders.Stats{GroupBy: req.GroupBy, Groups: make([]orders.StatsGroup, 0, len(rows))}
+	if req.ReportingCurrency == "" {
+		for _, row := range rows {
+			cur := statsCurrency(row.Currency)
+			g := orders.StatsGroup{
+				Key:     row.Key,
+				Orders:  row.Orders,
+				Items:   row.Items,
+				Revenue: money.Money{Amount: row.Revenue, Currency: cur},
+			}
+			if g.AvgOrderValue, err = average(g.Revenue, g.Orders); err != nil {
+				return orders.Stats{}, err
+			}
+			stats.Groups = append(stats.Groups, g)
+		}
+		return stats, nil
+	}
+
+	// Rows arrive sorted by key, so groups of one key are adjacent.
+	for _, row := range rows {
+		if row.MissingRates > 0 {
+			return orders.Stats{}, fmt.Errorf("%w: %d order(s) in %s %q paid in %s have no rate to %s",
+				orders.ErrNoExchangeRate, row.MissingRates, req.GroupBy, row.Key, row.Currency, target.Code)
+		}
+		last := len(stats.Groups) - 1
+		if last < 0 || stats.Groups[last].Key != row.Key {
+			stats.Groups = append(stats.Groups, orders.StatsGroup{Key: row.Key, Revenue: money.Money{Currency: target}})
+			last++
+		}
+		g := &stats.Groups[last]
+		g.Orders += row.Orders
+		g.Items += row.Items
+		if row.Rated == nil {
+			continue
+		}
+		from, err := money.LookupCurrency(row.Currency)
+		if err != nil {
+			return orders.Stats{}, fmt.Errorf("%s %q: %w", req.GroupBy, row.Key, err)
+		}
+		m, err := money.ConvertRated(row.Rated, from, target)
+		if err != nil {
+			return orders.Stats{}, err
+		}
+		g.Revenue.Amount += m.Amount
+	}
+	for i := range stats.Groups {
+		if stats.Groups[i].AvgOrderValue, err = average(stats.Groups[i].Revenue, stats.Groups[i].Orders); err != nil {
+			return orders.Stats{}, err
+		}
+	}
+	return stats, nil
+}
+
+// statsCurrency keeps groups of orders without a payment, or with a legacy
+// code, printable: they get a currency with no minor units.
+func statsCurrency(code string) money.Currency {
+	if c, err := money.LookupCurrency(code); err == nil {
+		return c
+	}
+	return money.Currency{Code: code}
+}
+
+func average(total money.Money, n int64) (money.Money, error) {
+	if n == 0 {
+		return money.Money{Currency: total.Currency}, nil
+	}
+	return money.ConvertRated(big.NewRat(total.Amount, n), total.Currency, total.Currency)
+}
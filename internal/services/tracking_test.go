@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/adapters/cache"
+	"github.com/reybrally/order-service/internal/app/orders"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// trackRepo resolves every track number to newest and counts the lookups.
+// Any other method panics.
+type trackRepo struct {
+	orders.OrderRepo
+	newest  domain.Order
+	lookups int
+}
+
+func (r *trackRepo) GetOrderByTrack(_ context.Context, trackNumber string) (domain.Order, error) {
+	r.lookups++
+	if trackNumber != r.newest.TrackNumber {
+		return domain.Order{}, orders.ErrNotFound
+	}
+	return r.newest, nil
+}
+
+func TestTrackOrderServesTheRepositoryChoice(t *testing.T) {
+	logging.InitLogger()
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	older := domain.Order{OrderUID: "older", TrackNumber: "TRK", DateCreated: at}
+	newer := domain.Order{OrderUID: "newer", TrackNumber: "TRK", DateCreated: at.Add(time.Hour)}
+	repo := &trackRepo{newest: newer}
+	c := cache.NewCacheService(10)
+	serv := NewOrderService(repo, c, nil, "orders-events", domain.ConsistencyStrict)
+	ctx := context.Background()
+
+	// The older order is cached, e.g. by GET /orders/older.
+	require.NoError(t, c.Set(older.OrderUID, older))
+	got, err := serv.TrackOrder(ctx, "TRK")
+	require.NoError(t, err)
+	assert.Equal(t, "newer", got.OrderUID)
+	assert.Equal(t, 1, repo.lookups)
+
+	got, err = serv.TrackOrder(ctx, "TRK")
+	require.NoError(t, err)
+	assert.Equal(t, "newer", got.OrderUID)
+	assert.Equal(t, 1, repo.lookups, "second lookup is served by the index")
+
+	// Caching the older order again makes the index ambiguous.
+	require.NoError(t, c.Set(older.OrderUID, older))
+	got, err = serv.TrackOrder(ctx, "TRK")
+	require.NoError(t, err)
+	assert.Equal(t, "newer", got.OrderUID)
+	assert.Equal(t, 2, repo.lookups)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/reybrally/order-service/internal/app/orders"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+const maxWarmupBatch = 100
+
+// WarmUpCache loads up to limit most recently created orders into the cache in
+// batches of batchSize. It returns the number of cached orders; on error or
+// ctx expiry the orders loaded so far are still cached. Orders are cached
+// oldest first, so in an LRU cache the newest orders are evicted last.
+func (serv *OrderService) WarmUpCache(ctx context.Context, limit, batchSize int) (int, error) {
+	if limit <= 0 {
+		return 0, nil
+	}
+	if batchSize <= 0 || batchSize > maxWarmupBatch {
+		batchSize = maxWarmupBatch
+	}
+
+	started := time.Now()
+	logging.LogInfo("Cache warm-up started", logrus.Fields{"limit": limit, "batch": batchSize})
+
+	var (
+		loaded []domain.Order
+		cursor string
+	)
+	for len(loaded) < limit {
+		size := batchSize
+		if rest := limit - len(loaded); rest < size {
+			size = rest
+		}
+
+		page, err := serv.repo.SearchOrders(ctx, orders.SearchFilters{}, orders.PageRequest{
+			Limit:   size,
+			SortBy:  "date_created",
+			SortDir: "desc",
+			Cursor:  cursor,
+		})
+		if err != nil {
+			logging.LogError("Cache warm-up batch failed", err, logrus.Fields{"loaded": len(loaded)})
+			serv.cacheOldestFirst(loaded)
+			return len(loaded), err
+		}
+
+		loaded = append(loaded, page.Orders...)
+		logging.LogInfo("Cache warm-up progress", logrus.Fields{"loaded": len(loaded), "limit": limit})
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	serv.cacheOldestFirst(loaded)
+	logging.LogInfo("Cache warm-up finished", logrus.Fields{"loaded": len(loaded), "took": time.Since(started).String()})
+	return len(loaded), nil
+}
+
+// cacheOldestFirst caches newestFirst in reverse.
+func (serv *OrderService) cacheOldestFirst(newestFirst []domain.Order) {
+	for i := len(newestFirst) - 1; i >= 0; i-- {
+		o := newestFirst[i]
+		if err := serv.cacheService.Set(o.OrderUID, o); err != nil {
+			logging.LogError("Cache warm-up set failed", err, logrus.Fields{"order_uid": o.OrderUID})
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/reybrally/order-service/internal/adapters/cache"
+	"github.com/reybrally/order-service/internal/app/orders"
+	domain "github.com/reybrally/order-service/internal/domain/order"
+	"github.com/reybrally/order-service/internal/logging"
+)
+
+// pagedRepo serves SearchOrders newest first from a fixed list, paging with
+// the offset as cursor; failAfter > 0 fails the page after that many orders.
+// Any other method panics.
+type pagedRepo struct {
+	orders.OrderRepo
+	newestFirst []domain.Order
+	failAfter   int
+}
+
+func (r *pagedRepo) SearchOrders(_ context.Context, _ orders.SearchFilters, req orders.PageRequest) (orders.Page, error) {
+	from, _ := strconv.Atoi(req.Cursor)
+	if r.failAfter > 0 && from >= r.failAfter {
+		return orders.Page{}, orders.ErrTimeout
+	}
+	to := min(from+req.Limit, len(r.newestFirst))
+	page := orders.Page{Orders: r.newestFirst[from:to]}
+	if to < len(r.newestFirst) {
+		page.NextCursor = strconv.Itoa(to)
+	}
+	return page, nil
+}
+
+func warmupOrders(n int) []domain.Order {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	out := make([]domain.Order, n)
+	for i := range out {
+		// out[0] is the newest.
+		out[i] = domain.Order{OrderUID: "o" + strconv.Itoa(i), DateCreated: base.Add(-time.Duration(i) * time.Hour)}
+	}
+	return out
+}
+
+func TestWarmUpCacheKeepsNewestOrders(t *testing.T) {
+	logging.InitLogger()
+	c := cache.NewCacheService(3)
+	serv := NewOrderService(&pagedRepo{newestFirst: warmupOrders(5)}, c, nil, "orders-events", domain.ConsistencyStrict)
+
+	n, err := serv.WarmUpCache(context.Background(), 5, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	for _, uid := range []string{"o0", "o1", "o2"} {
+		_, err := c.Get(uid)
+		assert.NoError(t, err, uid)
+	}
+	for _, uid := range []string{"o3", "o4"} {
+		_, err := c.Get(uid)
+		assert.Error(t, err, uid)
+	}
+}
+
+func TestWarmUpCacheEvictsOldestWarmedOrderFirst(t *testing.T) {
+	logging.InitLogger()
+	c := cache.NewCacheService(3)
+	serv := NewOrderService(&pagedRepo{newestFirst: warmupOrders(3)}, c, nil, "orders-events", domain.ConsistencyStrict)
+
+	_, err := serv.WarmUpCache(context.Background(), 3, 1)
+	require.NoError(t, err)
+	require.NoError(t, c.Set("new", domain.Order{OrderUID: "new"}))
+
+	_, err = c.Get("o2")
+	assert.Error(t, err, "the oldest warmed order must go first")
+	for _, uid := range []string{"o0", "o1", "new"} {
+		_, err := c.Get(uid)
+		assert.NoError(t, err, uid)
+	}
+}
+
+func TestWarmUpCacheKeepsPartialProgress(t *testing.T) {
+	logging.InitLogger()
+	c := cache.NewCacheService(10)
+	serv := NewOrderService(&pagedRepo{newestFirst: warmupOrders(5), failAfter: 2}, c, nil, "orders-events", domain.ConsistencyStrict)
+
+	n, err := serv.WarmUpCache(context.Background(), 5, 2)
+	assert.True(t, errors.Is(err, orders.ErrTimeout))
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, c.Len())
+}